@@ -2,12 +2,77 @@ package binding
 
 import "net/http"
 
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMETOML              = "application/toml"
+	MIMEMSGPACK           = "application/x-msgpack"
+	MIMEMSGPACK2          = "application/msgpack"
+	MIMEPROTOBUF          = "application/x-protobuf"
+)
+
 type Binding interface {
 	Name() string
 	Bind(r *http.Request, obj any) error
 }
 
+// BindingUri binds values that do not come from the request itself, such
+// as the route params of a matched path.
+type BindingUri interface {
+	Name() string
+	BindUri(m map[string][]string, obj any) error
+}
+
 var (
-	JSON = &jsonBinding{}
-	XML  = &xmlBinding{}
+	JSON          = &jsonBinding{}
+	XML           = &xmlBinding{}
+	Query         = &queryBinding{}
+	Form          = &formBinding{}
+	FormMultipart = &multipartBinding{}
+	Header        = &headerBinding{}
+	Uri           = &uriBinding{}
+	YAML          = &yamlBinding{}
+	TOML          = &tomlBinding{}
+	MsgPack       = &msgpackBinding{}
+	ProtoBuf      = &protobufBinding{}
 )
+
+// Default picks the binding for a request from its method and the media
+// type of its Content-Type header. Bodies are decoded by the binding of
+// their format; GET requests and form bodies bind from the query string
+// and form values, multipart bodies their files as well.
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet {
+		return Form
+	}
+	switch contentType {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	case MIMEYAML, MIMEYAML2:
+		return YAML
+	case MIMETOML:
+		return TOML
+	case MIMEMSGPACK, MIMEMSGPACK2:
+		return MsgPack
+	case MIMEPROTOBUF:
+		return ProtoBuf
+	default:
+		return Form
+	}
+}
+
+func validate(obj any) error {
+	if Validator == nil {
+		return nil
+	}
+	return Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,241 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type pageQuery struct {
+	Page     int           `form:"page" validate:"min=1"`
+	Size     *uint8        `form:"size"`
+	Tags     []string      `form:"tag"`
+	IDs      []int64       `form:"id"`
+	Active   bool          `form:"active"`
+	Ratio    float64       `form:"ratio"`
+	Since    time.Time     `form:"since"`
+	Timeout  time.Duration `form:"timeout"`
+	Ignored  string        `form:"-"`
+	Untagged string
+	Nested   struct {
+		Sort string `form:"sort"`
+	}
+}
+
+func TestQueryBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?page=2&size=20&tag=a&tag=b&id=1&id=2&active=true&ratio=0.5"+
+		"&since=2024-01-02T03:04:05Z&timeout=1m30s&Ignored=x&Untagged=y&sort=name", nil)
+	var q pageQuery
+	if err := Query.Bind(req, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 2 || q.Size == nil || *q.Size != 20 || len(q.Tags) != 2 || q.Tags[1] != "b" ||
+		len(q.IDs) != 2 || q.IDs[1] != 2 || !q.Active || q.Ratio != 0.5 || q.Timeout != 90*time.Second ||
+		q.Ignored != "" || q.Untagged != "y" || q.Nested.Sort != "name" {
+		t.Errorf("Unexpected binding %+v", q)
+	}
+	if !q.Since.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected time %v", q.Since)
+	}
+
+	var missing pageQuery
+	if err := Query.Bind(httptest.NewRequest(http.MethodGet, "/?page=2", nil), &missing); err != nil || missing.Size != nil {
+		t.Errorf("Expected a nil pointer for a missing value, got %v %v", missing.Size, err)
+	}
+	if err := Query.Bind(httptest.NewRequest(http.MethodGet, "/?page=x", nil), &q); err == nil {
+		t.Error("Expected a conversion error")
+	}
+	if err := Query.Bind(httptest.NewRequest(http.MethodGet, "/?page=0", nil), &q); err == nil {
+		t.Error("Expected a validation error")
+	}
+}
+
+func TestFormBinding(t *testing.T) {
+	body := url.Values{"name": {"gowave"}, "stars": {"42"}}.Encode()
+	req := httptest.NewRequest(http.MethodPost, "/?lang=go", strings.NewReader(body))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	var f struct {
+		Name  string `form:"name" validate:"required"`
+		Stars int    `form:"stars"`
+		Lang  string `form:"lang"`
+	}
+	if err := Form.Bind(req, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Name != "gowave" || f.Stars != 42 || f.Lang != "go" {
+		t.Errorf("Unexpected binding %+v", f)
+	}
+}
+
+func TestHeaderBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("X-Rate-Limit", "100")
+	var h struct {
+		RequestID string `header:"x-request-id" validate:"required"`
+		Limit     int    `header:"X-Rate-Limit"`
+	}
+	if err := Header.Bind(req, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.RequestID != "abc" || h.Limit != 100 {
+		t.Errorf("Unexpected binding %+v", h)
+	}
+}
+
+func TestUriBinding(t *testing.T) {
+	var u struct {
+		ID   int    `uri:"id" validate:"required"`
+		Name string `uri:"name"`
+	}
+	if err := Uri.BindUri(map[string][]string{"id": {"7"}, "name": {"bob"}}, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 7 || u.Name != "bob" {
+		t.Errorf("Unexpected binding %+v", u)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		method, contentType string
+		want                Binding
+	}{
+		{http.MethodGet, MIMEJSON, Form},
+		{http.MethodPost, MIMEJSON, JSON},
+		{http.MethodPut, MIMEXML2, XML},
+		{http.MethodPost, MIMEMultipartPOSTForm, FormMultipart},
+	}
+	for _, tt := range tests {
+		if got := Default(tt.method, tt.contentType); got != tt.want {
+			t.Errorf("%s %s: expected %s, got %s", tt.method, tt.contentType, tt.want.Name(), got.Name())
+		}
+	}
+}
+
+type listRequest struct {
+	Page    int       `form:"page" json:"page" default:"1" binding:"min=1"`
+	Size    *int      `form:"size" json:"size" default:"20"`
+	Sort    []string  `form:"sort" json:"sort" default:"created,id"`
+	Keyword string    `form:"q" json:"q" binding:"required"`
+	From    time.Time `form:"from" json:"-" time_format:"2006-01-02"`
+	Until   time.Time `form:"until" json:"-" time_format:"unix"`
+	Filter  struct {
+		Status string `form:"status" json:"status" default:"open"`
+	} `json:"filter"`
+}
+
+func TestBindingDefaults(t *testing.T) {
+	var q listRequest
+	req := httptest.NewRequest(http.MethodGet, "/?q=go&page=3&from=2024-05-06&until=1700000000", nil)
+	if err := Query.Bind(req, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Page != 3 || q.Size == nil || *q.Size != 20 || strings.Join(q.Sort, ",") != "created,id" || q.Filter.Status != "open" {
+		t.Errorf("Unexpected query binding %+v", q)
+	}
+	if !q.From.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) || q.Until.Unix() != 1700000000 {
+		t.Errorf("Unexpected times %v %v", q.From, q.Until)
+	}
+
+	var j listRequest
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"q":"go","sort":["name"],"filter":{}}`))
+	if err := JSON.Bind(req, &j); err != nil {
+		t.Fatal(err)
+	}
+	if j.Page != 1 || j.Size == nil || *j.Size != 20 || strings.Join(j.Sort, ",") != "name" || j.Filter.Status != "open" {
+		t.Errorf("Unexpected JSON binding %+v", j)
+	}
+
+	kept := listRequest{Page: 5}
+	if err := Query.Bind(httptest.NewRequest(http.MethodGet, "/?q=go", nil), &kept); err != nil || kept.Page != 5 {
+		t.Errorf("Expected the preset page to be kept, got %d %v", kept.Page, err)
+	}
+}
+
+func TestBindingShortcutTags(t *testing.T) {
+	tests := []struct {
+		query, field, tag string
+	}{
+		{"/?page=2", "q", "required"},
+		{"/?q=go&page=0", "page", "min"},
+		{"/?q=go&from=06/05/2024", "", ""},
+	}
+	for _, tt := range tests {
+		var q listRequest
+		err := Query.Bind(httptest.NewRequest(http.MethodGet, tt.query, nil), &q)
+		if tt.tag == "" {
+			if err == nil || isValidationErrors(err) {
+				t.Errorf("%s: expected a parse error, got %v", tt.query, err)
+			}
+			continue
+		}
+		var errs ValidationErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != tt.field || errs[0].Tag != tt.tag {
+			t.Errorf("%s: expected %s to fail %s, got %v", tt.query, tt.field, tt.tag, err)
+		}
+	}
+}
+
+type config struct {
+	Name    string   `yaml:"name" toml:"name" msgpack:"name" validate:"required"`
+	Port    int      `yaml:"port" toml:"port" msgpack:"port" default:"8080"`
+	Debug   bool     `yaml:"debug" toml:"debug" msgpack:"debug"`
+	Aliases []string `yaml:"aliases" toml:"aliases" msgpack:"aliases"`
+}
+
+func TestBodyBindings(t *testing.T) {
+	packed, _ := msgpack.Marshal(map[string]any{"name": "api", "debug": true, "aliases": []string{"a"}})
+	unnamed, _ := msgpack.Marshal(map[string]any{"debug": true})
+	tests := []struct {
+		contentType   string
+		body, missing string
+	}{
+		{MIMEYAML, "name: api\ndebug: true\naliases: [a]\n", "debug: true\n"},
+		{MIMEYAML2, "name: api\ndebug: true\naliases: [a]\n", "debug: true\n"},
+		{MIMETOML, "name = \"api\"\ndebug = true\naliases = [\"a\"]\n", "debug = true\n"},
+		{MIMEMSGPACK, string(packed), string(unnamed)},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		b := Default(req.Method, tt.contentType)
+		var c config
+		if err := b.Bind(req, &c); err != nil {
+			t.Errorf("%s: %v", tt.contentType, err)
+			continue
+		}
+		if c.Name != "api" || c.Port != 8080 || !c.Debug || len(c.Aliases) != 1 || c.Aliases[0] != "a" {
+			t.Errorf("%s: unexpected binding %+v", tt.contentType, c)
+		}
+		var missing config
+		req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.missing))
+		if err := b.Bind(req, &missing); !isValidationErrors(err) {
+			t.Errorf("%s: expected a validation error, got %v", tt.contentType, err)
+		}
+	}
+}
+
+func TestProtoBufBinding(t *testing.T) {
+	body, err := proto.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	var msg wrapperspb.StringValue
+	if err := Default(req.Method, MIMEPROTOBUF).Bind(req, &msg); err != nil || msg.GetValue() != "hello" {
+		t.Errorf("Expected hello, got %q %v", msg.GetValue(), err)
+	}
+	var notProto config
+	if err := ProtoBuf.Bind(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), &notProto); err == nil {
+		t.Error("Expected an error for a non-proto target")
+	}
+}
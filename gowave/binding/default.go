@@ -0,0 +1,58 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// setDefaults fills zero-valued fields of the struct ptr points to from
+// their `default` tags. Slice and array defaults are comma separated, e.g.
+// `default:"a,b"`. Bindings call it before reading the request, so values
+// in the request replace the defaults and values the caller already set
+// are kept.
+func setDefaults(ptr any) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("binding: obj must be a non-nil pointer")
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return setStructDefaults(v)
+}
+
+func setStructDefaults(value reflect.Value) error {
+	t := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		field := value.Field(i)
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			if !field.IsZero() {
+				continue
+			}
+			values := []string{def}
+			if field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+				values = strings.Split(def, ",")
+			}
+			if err := setValues(field, sf, values); err != nil {
+				return fmt.Errorf("binding: default of field %s: %w", sf.Name, err)
+			}
+			continue
+		}
+		if field.Kind() == reflect.Ptr && !field.IsNil() {
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Struct && !isScalarStruct(field.Type()) && !isFileType(field.Type()) {
+			if err := setStructDefaults(field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
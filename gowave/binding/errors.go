@@ -0,0 +1,112 @@
+package binding
+
+import (
+	"errors"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"` // path of the field, e.g. "items[0].name"
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+
+	err validator.FieldError
+	uni *ut.UniversalTranslator
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is returned by bindings when validation fails.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Map returns the messages keyed by field path.
+func (errs ValidationErrors) Map() map[string]string {
+	m := make(map[string]string, len(errs))
+	for _, e := range errs {
+		m[e.Field] = e.Message
+	}
+	return m
+}
+
+// Translate returns a copy of errs with messages in the first of locales
+// that has been registered. Locales such as "zh-CN" fall back to "zh", and
+// DefaultLocale is used if none match.
+func (errs ValidationErrors) Translate(locales ...string) ValidationErrors {
+	out := make(ValidationErrors, len(errs))
+	for i, e := range errs {
+		if e.err != nil {
+			e.Message = e.err.Translate(findTranslator(e.uni, locales...))
+		}
+		out[i] = e
+	}
+	return out
+}
+
+func (errs ValidationErrors) withPrefix(prefix string) ValidationErrors {
+	for i := range errs {
+		if strings.HasPrefix(errs[i].Field, "[") {
+			errs[i].Field = prefix + errs[i].Field
+		} else {
+			errs[i].Field = prefix + "." + errs[i].Field
+		}
+	}
+	return errs
+}
+
+func toValidationErrors(err error, uni *ut.UniversalTranslator) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	trans := findTranslator(uni, DefaultLocale)
+	errs := make(ValidationErrors, len(verrs))
+	for i, fe := range verrs {
+		// drop the name of the validated struct from the namespace
+		_, path, _ := strings.Cut(fe.Namespace(), ".")
+		errs[i] = FieldError{
+			Field:   path,
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+			err:     fe,
+			uni:     uni,
+		}
+	}
+	return errs
+}
+
+func isValidationErrors(err error) bool {
+	var errs ValidationErrors
+	return errors.As(err, &errs)
+}
+
+// mergeValidationErrors joins the ValidationErrors of a and b; either may
+// be nil.
+func mergeValidationErrors(a, b error) error {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	var errsA, errsB ValidationErrors
+	if !errors.As(a, &errsA) || !errors.As(b, &errsB) {
+		return errors.Join(a, b)
+	}
+	return append(errsA, errsB...)
+}
@@ -0,0 +1,257 @@
+package binding
+
+import (
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// registerFileValidations adds the tags used on multipart file fields:
+//
+//	filesize=2MB               every file is at most 2MB
+//	totalsize=10MB             all files of the field together are at most 10MB
+//	mimetype=image/png image/* every file's sniffed type is in the list
+//
+// A malformed size fails validation; the multipart binding reports it as
+// an error before reading the body.
+func registerFileValidations(v *validator.Validate) {
+	_ = v.RegisterValidation("filesize", validateFileSize)
+	_ = v.RegisterValidation("totalsize", validateTotalSize)
+	_ = v.RegisterValidation("mimetype", validateMimeType)
+}
+
+func validateFileSize(fl validator.FieldLevel) bool {
+	limit, err := cachedSize(fl.Param())
+	if err != nil {
+		return false
+	}
+	files, ok := fileHeaders(fl.Field())
+	if !ok {
+		return false
+	}
+	for _, file := range files {
+		if file.Size > limit {
+			return false
+		}
+	}
+	return true
+}
+
+func validateTotalSize(fl validator.FieldLevel) bool {
+	limit, err := cachedSize(fl.Param())
+	if err != nil {
+		return false
+	}
+	files, ok := fileHeaders(fl.Field())
+	if !ok {
+		return false
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	return total <= limit
+}
+
+func validateMimeType(fl validator.FieldLevel) bool {
+	files, ok := fileHeaders(fl.Field())
+	if !ok {
+		return false
+	}
+	allowed := strings.Fields(fl.Param())
+	for _, file := range files {
+		if !matchMimeType(fileContentType(file), allowed) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchMimeType(contentType string, allowed []string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	contentType = strings.TrimSpace(contentType)
+	for _, a := range allowed {
+		if a == contentType || a == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// fileHeaders returns the files held by a file field; the validator has
+// already dereferenced pointer fields. ok is false if field does not hold
+// files, so that a file tag on another type fails validation.
+func fileHeaders(field reflect.Value) (files []*multipart.FileHeader, ok bool) {
+	if !isFileType(field.Type()) {
+		return nil, false
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			sub, _ := fileHeaders(field.Index(i))
+			files = append(files, sub...)
+		}
+		return files, true
+	case reflect.Ptr:
+		if field.IsNil() {
+			return nil, true
+		}
+		return fileHeaders(field.Elem())
+	}
+	file := field.Interface().(multipart.FileHeader)
+	return []*multipart.FileHeader{&file}, true
+}
+
+// multipartOverhead is the room left beyond the file size limits for part
+// headers, boundaries and plain form values.
+const multipartOverhead = 1 << 20
+
+type bodyLimitResult struct {
+	limit int64
+	err   error
+}
+
+var (
+	sizeCache      sync.Map // tag param -> int64
+	bodyLimitCache sync.Map // reflect.Type -> bodyLimitResult
+)
+
+// bodyLimit returns the largest multipart body that binding obj can need
+// without breaking the totalsize and filesize limits of its file fields, or
+// 0 if some file field has no limit. It fails on a malformed size.
+func bodyLimit(obj any) (int64, error) {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return 0, nil
+	}
+	if cached, ok := bodyLimitCache.Load(t); ok {
+		res := cached.(bodyLimitResult)
+		return res.limit, res.err
+	}
+	total, bounded, err := fileFieldsLimit(t)
+	var limit int64
+	if err == nil && bounded && total > 0 {
+		limit = total + multipartOverhead
+	}
+	bodyLimitCache.Store(t, bodyLimitResult{limit: limit, err: err})
+	return limit, err
+}
+
+// fileFieldsLimit sums the limits of the file fields of struct type t,
+// walking nested structs the way the multipart binding maps them. bounded
+// is false if a file field has no limit.
+func fileFieldsLimit(t reflect.Type) (total int64, bounded bool, err error) {
+	return fileFieldsLimitOf(t, map[reflect.Type]bool{})
+}
+
+// fileFieldsLimitOf skips the types in seen, which are being walked
+// already, so that a recursive type ends the walk.
+func fileFieldsLimitOf(t reflect.Type, seen map[reflect.Type]bool) (total int64, bounded bool, err error) {
+	if seen[t] {
+		return 0, true, nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	bounded = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("form") == "-" || field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr && !isFileType(ft) {
+			ft = ft.Elem()
+		}
+		var limit int64
+		switch {
+		case isFileType(ft):
+			limit, err = fileFieldLimit(field, ft)
+		case ft.Kind() == reflect.Struct && !isScalarStruct(ft):
+			var subBounded bool
+			limit, subBounded, err = fileFieldsLimitOf(ft, seen)
+			if !subBounded {
+				limit = -1
+			}
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if limit < 0 {
+			bounded = false
+			continue
+		}
+		total += limit
+	}
+	return total, bounded, nil
+}
+
+// fileFieldLimit returns the most a file field can hold according to its
+// totalsize tag, or its filesize tag if it holds a single file, or -1 if
+// it is unbounded. t is the field type without pointers to a file slice.
+func fileFieldLimit(field reflect.StructField, t reflect.Type) (int64, error) {
+	single := t.Kind() != reflect.Slice && t.Kind() != reflect.Array
+	limit := int64(-1)
+	for _, tagName := range []string{"validate", "binding"} {
+		for _, rule := range strings.Split(field.Tag.Get(tagName), ",") {
+			name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			if name != "filesize" && name != "totalsize" {
+				continue
+			}
+			size, err := cachedSize(param)
+			if err != nil {
+				return 0, fmt.Errorf("binding: field %s: %w", field.Name, err)
+			}
+			if name == "filesize" && !single {
+				continue
+			}
+			if limit < 0 || size < limit {
+				limit = size
+			}
+		}
+	}
+	return limit, nil
+}
+
+func cachedSize(param string) (int64, error) {
+	if n, ok := sizeCache.Load(param); ok {
+		return n.(int64), nil
+	}
+	n, err := parseSize(param)
+	if err != nil {
+		return 0, err
+	}
+	sizeCache.Store(param, n)
+	return n, nil
+}
+
+// parseSize parses sizes like 512, 64KB or 2MB.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		n      int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+	num := strings.ToUpper(strings.TrimSpace(s))
+	mul := int64(1)
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, mul = strings.TrimSpace(n), u.n
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad size %q", s)
+	}
+	return n * mul, nil
+}
@@ -0,0 +1,29 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+)
+
+const defaultMemory = 32 << 20 // 32 MB
+
+type formBinding struct {
+}
+
+func (f *formBinding) Name() string {
+	return "form"
+}
+
+// Bind binds the query string and the urlencoded or multipart body.
+func (f *formBinding) Bind(r *http.Request, obj any) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if err := r.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	if err := mapFormByTag(obj, r.Form, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
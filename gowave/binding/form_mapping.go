@@ -0,0 +1,288 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errUnknownType      = errors.New("unknown type")
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// setter looks up the values stored under key and sets them on value.
+type setter interface {
+	TrySet(value reflect.Value, field reflect.StructField, key string) (bool, error)
+}
+
+type formSource map[string][]string
+
+func (form formSource) TrySet(value reflect.Value, field reflect.StructField, key string) (bool, error) {
+	values, ok := form[key]
+	if !ok {
+		return false, nil
+	}
+	return true, setValues(value, field, values)
+}
+
+type headerSource http.Header
+
+func (h headerSource) TrySet(value reflect.Value, field reflect.StructField, key string) (bool, error) {
+	values, ok := h[textproto.CanonicalMIMEHeaderKey(key)]
+	if !ok {
+		return false, nil
+	}
+	return true, setValues(value, field, values)
+}
+
+func mapFormByTag(ptr any, form map[string][]string, tag string) error {
+	return mappingByPtr(ptr, formSource(form), tag)
+}
+
+func mapHeader(ptr any, h http.Header) error {
+	return mappingByPtr(ptr, headerSource(h), "header")
+}
+
+// mappingByPtr fills the struct ptr points to from s. Each field is looked
+// up under its tag name, or its Go name if untagged; a "-" tag skips it.
+// Untagged struct fields are descended into. Fields missing from s keep
+// their `default` tag value.
+func mappingByPtr(ptr any, s setter, tag string) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("binding: obj must be a non-nil pointer")
+	}
+	if err := setDefaults(ptr); err != nil {
+		return err
+	}
+	_, err := mapping(v.Elem(), reflect.StructField{}, s, tag)
+	return err
+}
+
+func mapping(value reflect.Value, field reflect.StructField, s setter, tag string) (bool, error) {
+	if field.Tag.Get(tag) == "-" {
+		return false, nil
+	}
+	if isFileType(value.Type()) {
+		if field.Name == "" {
+			return false, nil
+		}
+		return tryToSetValue(value, field, s, tag)
+	}
+	if value.Kind() == reflect.Ptr {
+		isNew := value.IsNil()
+		ptr := value
+		if isNew {
+			ptr = reflect.New(value.Type().Elem())
+		}
+		isSet, err := mapping(ptr.Elem(), field, s, tag)
+		if err != nil {
+			return false, err
+		}
+		if isNew && isSet {
+			value.Set(ptr)
+		}
+		return isSet, nil
+	}
+	if value.Kind() != reflect.Struct || isScalarStruct(value.Type()) {
+		if field.Name == "" {
+			return false, nil
+		}
+		return tryToSetValue(value, field, s, tag)
+	}
+	isSet := false
+	t := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		ok, err := mapping(value.Field(i), sf, s, tag)
+		if err != nil {
+			return false, err
+		}
+		isSet = isSet || ok
+	}
+	return isSet, nil
+}
+
+// isScalarStruct reports whether a struct is set from a single value, like
+// time.Time, rather than field by field.
+func isScalarStruct(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func tryToSetValue(value reflect.Value, field reflect.StructField, s setter, tag string) (bool, error) {
+	key, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if key == "" {
+		key = field.Name
+	}
+	isSet, err := s.TrySet(value, field, key)
+	if err != nil {
+		return false, fmt.Errorf("binding: field %s: %w", field.Name, err)
+	}
+	return isSet, nil
+}
+
+func setValues(value reflect.Value, field reflect.StructField, values []string) error {
+	switch value.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(slice.Index(i), field, v); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) != value.Len() {
+			return fmt.Errorf("%q is not valid value for %s", values, value.Type())
+		}
+		for i, v := range values {
+			if err := setValue(value.Index(i), field, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if len(values) == 0 {
+			return nil
+		}
+		return setValue(value, field, values[0])
+	}
+}
+
+func setValue(value reflect.Value, field reflect.StructField, s string) error {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setValue(value.Elem(), field, s)
+	}
+	if layout := field.Tag.Get("time_format"); layout != "" && value.Type() == timeType {
+		return setTime(value, layout, s)
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		if s == "" {
+			return nil
+		}
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() == reflect.TypeOf(time.Duration(0)) {
+			return setDuration(value, s)
+		}
+		return setInt(value, s)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setUint(value, s)
+	case reflect.Float32, reflect.Float64:
+		return setFloat(value, s)
+	case reflect.Bool:
+		return setBool(value, s)
+	case reflect.String:
+		value.SetString(s)
+		return nil
+	case reflect.Interface:
+		value.Set(reflect.ValueOf(s))
+		return nil
+	default:
+		return errUnknownType
+	}
+}
+
+func setInt(value reflect.Value, s string) error {
+	if s == "" {
+		s = "0"
+	}
+	n, err := strconv.ParseInt(s, 10, value.Type().Bits())
+	if err == nil {
+		value.SetInt(n)
+	}
+	return err
+}
+
+func setUint(value reflect.Value, s string) error {
+	if s == "" {
+		s = "0"
+	}
+	n, err := strconv.ParseUint(s, 10, value.Type().Bits())
+	if err == nil {
+		value.SetUint(n)
+	}
+	return err
+}
+
+func setFloat(value reflect.Value, s string) error {
+	if s == "" {
+		s = "0"
+	}
+	n, err := strconv.ParseFloat(s, value.Type().Bits())
+	if err == nil {
+		value.SetFloat(n)
+	}
+	return err
+}
+
+func setBool(value reflect.Value, s string) error {
+	if s == "" {
+		s = "false"
+	}
+	b, err := strconv.ParseBool(s)
+	if err == nil {
+		value.SetBool(b)
+	}
+	return err
+}
+
+func setDuration(value reflect.Value, s string) error {
+	if s == "" {
+		s = "0"
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil {
+		value.SetInt(int64(d))
+	}
+	return err
+}
+
+// setTime parses s with a time_format layout; "unix", "unixmilli" and
+// "unixnano" parse integer timestamps instead.
+func setTime(value reflect.Value, layout, s string) error {
+	if s == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	var t time.Time
+	switch layout {
+	case "unix", "unixmilli", "unixnano":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		switch layout {
+		case "unix":
+			t = time.Unix(n, 0)
+		case "unixmilli":
+			t = time.UnixMilli(n)
+		default:
+			t = time.Unix(0, n)
+		}
+	default:
+		var err error
+		if t, err = time.Parse(layout, s); err != nil {
+			return err
+		}
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
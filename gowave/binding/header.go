@@ -0,0 +1,17 @@
+package binding
+
+import "net/http"
+
+type headerBinding struct {
+}
+
+func (h *headerBinding) Name() string {
+	return "header"
+}
+
+func (h *headerBinding) Bind(r *http.Request, obj any) error {
+	if err := mapHeader(obj, r.Header); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -1,9 +1,10 @@
 package binding
 
 import (
-	"encoding/json"
 	"errors"
 	"net/http"
+
+	"github.com/ChenGuo505/gowave/codec"
 )
 
 type jsonBinding struct {
@@ -20,7 +21,10 @@ func (j *jsonBinding) Bind(r *http.Request, obj any) error {
 	if body == nil {
 		return errors.New("request body is nil")
 	}
-	decoder := json.NewDecoder(body)
+	if err := setDefaults(obj); err != nil {
+		return err
+	}
+	decoder := codec.JSON.NewDecoder(body)
 	if j.DisallowUnknownFields {
 		decoder.DisallowUnknownFields()
 	}
@@ -0,0 +1,28 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type msgpackBinding struct {
+}
+
+func (m *msgpackBinding) Name() string {
+	return "msgpack"
+}
+
+func (m *msgpackBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("request body is nil")
+	}
+	if err := setDefaults(obj); err != nil {
+		return err
+	}
+	if err := msgpack.NewDecoder(r.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
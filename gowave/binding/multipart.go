@@ -0,0 +1,109 @@
+package binding
+
+import (
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+)
+
+var (
+	fileHeaderType    = reflect.TypeOf(multipart.FileHeader{})
+	fileHeaderPtrType = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+type multipartBinding struct {
+	MaxMemory int64 // bytes of file parts kept in memory, the rest go to temp files
+}
+
+func (m *multipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+// Bind binds the form values and files of a multipart body. Fields of type
+// *multipart.FileHeader, multipart.FileHeader or []*multipart.FileHeader are
+// set from the uploaded files, everything else from the form values. When
+// every file field has a totalsize or filesize limit, a body that exceeds
+// them is cut off with an *http.MaxBytesError before it is read in full.
+func (m *multipartBinding) Bind(r *http.Request, obj any) error {
+	limit, err := bodyLimit(obj)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && r.Body != nil && r.MultipartForm == nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, limit)
+	}
+	maxMemory := m.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMemory
+	}
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return err
+	}
+	if err := mappingByPtr(obj, (*multipartSource)(r), "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type multipartSource http.Request
+
+func (r *multipartSource) TrySet(value reflect.Value, field reflect.StructField, key string) (bool, error) {
+	if isFileType(value.Type()) {
+		files := r.MultipartForm.File[key]
+		if len(files) == 0 {
+			return false, nil
+		}
+		return true, setFiles(value, files)
+	}
+	return formSource(r.Form).TrySet(value, field, key)
+}
+
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t == fileHeaderType || t == fileHeaderPtrType
+}
+
+func setFiles(value reflect.Value, files []*multipart.FileHeader) error {
+	switch value.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(files), len(files))
+		for i, file := range files {
+			setFile(slice.Index(i), file)
+		}
+		value.Set(slice)
+	case reflect.Array:
+		for i := 0; i < value.Len() && i < len(files); i++ {
+			setFile(value.Index(i), files[i])
+		}
+	default:
+		setFile(value, files[0])
+	}
+	return nil
+}
+
+func setFile(value reflect.Value, file *multipart.FileHeader) {
+	if value.Type() == fileHeaderPtrType {
+		value.Set(reflect.ValueOf(file))
+		return
+	}
+	value.Set(reflect.ValueOf(*file))
+}
+
+// fileContentType sniffs the media type of an uploaded file from its first
+// bytes, falling back to the Content-Type the client sent for the part.
+func fileContentType(file *multipart.FileHeader) string {
+	f, err := file.Open()
+	if err != nil {
+		return textproto.MIMEHeader(file.Header).Get("Content-Type")
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	if n == 0 {
+		return textproto.MIMEHeader(file.Header).Get("Content-Type")
+	}
+	return http.DetectContentType(buf[:n])
+}
@@ -0,0 +1,166 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type upload struct {
+	Title  string                  `form:"title" validate:"required"`
+	Avatar *multipart.FileHeader   `form:"avatar" validate:"required,filesize=1KB,mimetype=image/png image/jpeg"`
+	Files  []*multipart.FileHeader `form:"files" validate:"omitempty,max=3,filesize=1KB,totalsize=2KB"`
+	Cover  multipart.FileHeader    `form:"cover"`
+}
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string][][]byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		_ = mw.WriteField(k, v)
+	}
+	for k, contents := range files {
+		for i, content := range contents {
+			w, err := mw.CreateFormFile(k, k+string(rune('0'+i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write(content)
+		}
+	}
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestMultipartBinding(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"title": "hello"}, map[string][][]byte{
+		"avatar": {append(pngHeader, "data"...)},
+		"files":  {[]byte("a"), []byte("bb")},
+		"cover":  {[]byte("cover")},
+	})
+	var u upload
+	if err := FormMultipart.Bind(req, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Title != "hello" || u.Avatar == nil || u.Avatar.Filename != "avatar0" || len(u.Files) != 2 ||
+		u.Files[1].Size != 2 || u.Cover.Filename != "cover0" {
+		t.Errorf("Unexpected binding %+v", u)
+	}
+}
+
+func TestMultipartBindingLimits(t *testing.T) {
+	png := append(pngHeader, "data"...)
+	big := bytes.Repeat([]byte("x"), 1025)
+	tests := []struct {
+		name  string
+		files map[string][][]byte
+		tag   string
+	}{
+		{"missing file", map[string][][]byte{}, "required"},
+		{"file too large", map[string][][]byte{"avatar": {append(png, big...)}}, "filesize"},
+		{"mime not allowed", map[string][][]byte{"avatar": {[]byte("plain text")}}, "mimetype"},
+		{"one of many too large", map[string][][]byte{"avatar": {png}, "files": {[]byte("a"), big}}, "filesize"},
+		{"total too large", map[string][][]byte{"avatar": {png}, "files": {big[:1000], big[:1000], big[:1000]}}, "totalsize"},
+		{"too many files", map[string][][]byte{"avatar": {png}, "files": {{1}, {2}, {3}, {4}}}, "max"},
+	}
+	for _, tt := range tests {
+		var u upload
+		err := FormMultipart.Bind(newMultipartRequest(t, map[string]string{"title": "x"}, tt.files), &u)
+		var errs ValidationErrors
+		if !errors.As(err, &errs) || errs[0].Tag != tt.tag {
+			t.Errorf("%s: expected a %s error, got %v", tt.name, tt.tag, err)
+		}
+	}
+}
+
+func TestMultipartBindingNotMultipart(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("title=x"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	var u upload
+	if err := FormMultipart.Bind(req, &u); err == nil {
+		t.Error("Expected an error for a non-multipart body")
+	}
+}
+
+func TestMultipartBindingBodyLimit(t *testing.T) {
+	type limited struct {
+		Avatar *multipart.FileHeader   `form:"avatar" validate:"filesize=1KB"`
+		Files  []*multipart.FileHeader `form:"files" binding:"totalsize=2KB"`
+	}
+	if limit, err := bodyLimit(&limited{}); err != nil || limit != 3<<10+multipartOverhead {
+		t.Errorf("Expected a limit of 3KB plus overhead, got %d %v", limit, err)
+	}
+	if limit, err := bodyLimit(&upload{}); err != nil || limit != 0 {
+		t.Errorf("Expected no limit with an unbounded file field, got %d %v", limit, err)
+	}
+	type nested struct {
+		Profile struct {
+			Avatar *multipart.FileHeader `form:"avatar" validate:"filesize=1KB"`
+		}
+		Docs *struct {
+			Files *[]*multipart.FileHeader `form:"files" validate:"totalsize=2KB"`
+		}
+		Skipped struct {
+			Files []*multipart.FileHeader `form:"skipped"`
+		} `form:"-"`
+	}
+	if limit, err := bodyLimit(&nested{}); err != nil || limit != 3<<10+multipartOverhead {
+		t.Errorf("Expected a limit of 3KB plus overhead for nested structs, got %d %v", limit, err)
+	}
+	type nestedUnbounded struct {
+		nested
+		Extra struct {
+			Files []*multipart.FileHeader `form:"extra"`
+		}
+	}
+	if limit, err := bodyLimit(&nestedUnbounded{}); err != nil || limit != 0 {
+		t.Errorf("Expected no limit with an unbounded nested file field, got %d %v", limit, err)
+	}
+
+	huge := bytes.Repeat([]byte("x"), multipartOverhead+4<<10)
+	var l limited
+	err := FormMultipart.Bind(newMultipartRequest(t, nil, map[string][][]byte{"files": {huge}}), &l)
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("Expected a MaxBytesError, got %v", err)
+	}
+
+	type malformed struct {
+		Avatar *multipart.FileHeader `form:"avatar" validate:"filesize=lots"`
+	}
+	var m malformed
+	if err := FormMultipart.Bind(newMultipartRequest(t, nil, nil), &m); err == nil || !strings.Contains(err.Error(), "lots") {
+		t.Errorf("Expected an error for a malformed size, got %v", err)
+	}
+
+	type misused struct {
+		Title string `form:"title" validate:"filesize=1KB"`
+	}
+	var mu misused
+	err = FormMultipart.Bind(newMultipartRequest(t, map[string]string{"title": "x"}, nil), &mu)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || errs[0].Tag != "filesize" {
+		t.Errorf("Expected a filesize error on a non-file field, got %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"512": 512, "64KB": 64 << 10, "2mb": 2 << 20, "1GB": 1 << 30, "10B": 10} {
+		if got, err := parseSize(s); err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d %v", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"", "MB", "-1KB", "1.5MB"} {
+		if _, err := parseSize(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package binding
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type protobufBinding struct {
+}
+
+func (p *protobufBinding) Name() string {
+	return "protobuf"
+}
+
+// Bind decodes the body into obj, which must be a proto.Message. Messages
+// have no struct tags, so defaults and validation don't apply.
+func (p *protobufBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("request body is nil")
+	}
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return errors.New("binding: obj must be a proto.Message")
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(body, msg)
+}
@@ -0,0 +1,17 @@
+package binding
+
+import "net/http"
+
+type queryBinding struct {
+}
+
+func (q *queryBinding) Name() string {
+	return "query"
+}
+
+func (q *queryBinding) Bind(r *http.Request, obj any) error {
+	if err := mapFormByTag(obj, r.URL.Query(), "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -0,0 +1,28 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BurntSushi/toml"
+)
+
+type tomlBinding struct {
+}
+
+func (t *tomlBinding) Name() string {
+	return "toml"
+}
+
+func (t *tomlBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("request body is nil")
+	}
+	if err := setDefaults(obj); err != nil {
+		return err
+	}
+	if _, err := toml.NewDecoder(r.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -0,0 +1,88 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	zhtranslations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// DefaultLocale is the language of the messages in ValidationErrors
+// returned by the default Validator.
+var DefaultLocale = "en"
+
+var fileTranslations = map[string]map[string]string{
+	"en": {
+		"filesize":  "{0} must be at most {1}",
+		"totalsize": "{0} must be at most {1} in total",
+		"mimetype":  "{0} must be one of {1}",
+	},
+	"zh": {
+		"filesize":  "{0}不能超过{1}",
+		"totalsize": "{0}总大小不能超过{1}",
+		"mimetype":  "{0}的类型必须是{1}之一",
+	},
+}
+
+// newTranslator returns the translators of a validator with the built-in
+// locales registered. Translators can't be shared between validators since
+// a message can only be added to a translator once.
+func newTranslator(v *validator.Validate) *ut.UniversalTranslator {
+	uni := ut.New(en.New())
+	_ = registerLocale(v, uni, en.New(), entranslations.RegisterDefaultTranslations)
+	_ = registerLocale(v, uni, zh.New(), zhtranslations.RegisterDefaultTranslations)
+	for locale, messages := range fileTranslations {
+		for tag, text := range messages {
+			_ = registerTranslation(v, uni, locale, tag, text)
+		}
+	}
+	return uni
+}
+
+func registerLocale(v *validator.Validate, uni *ut.UniversalTranslator, l locales.Translator, register func(*validator.Validate, ut.Translator) error) error {
+	if err := uni.AddTranslator(l, true); err != nil {
+		return err
+	}
+	trans, _ := uni.GetTranslator(l.Locale())
+	return register(v, trans)
+}
+
+func registerTranslation(v *validator.Validate, uni *ut.UniversalTranslator, locale, tag, text string) error {
+	trans, found := uni.GetTranslator(locale)
+	if !found {
+		return fmt.Errorf("binding: locale %s is not registered", locale)
+	}
+	return v.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+		return trans.Add(tag, text, true)
+	}, func(trans ut.Translator, fe validator.FieldError) string {
+		msg, err := trans.T(tag, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return msg
+	})
+}
+
+func findTranslator(uni *ut.UniversalTranslator, locales ...string) ut.Translator {
+	for _, locale := range locales {
+		locale = strings.ReplaceAll(strings.TrimSpace(locale), "-", "_")
+		if trans, found := uni.GetTranslator(locale); found {
+			return trans
+		}
+		if lang, _, ok := strings.Cut(locale, "_"); ok {
+			if trans, found := uni.GetTranslator(lang); found {
+				return trans
+			}
+		}
+	}
+	if trans, found := uni.GetTranslator(DefaultLocale); found {
+		return trans
+	}
+	return uni.GetFallback()
+}
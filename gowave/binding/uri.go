@@ -0,0 +1,15 @@
+package binding
+
+type uriBinding struct {
+}
+
+func (u *uriBinding) Name() string {
+	return "uri"
+}
+
+func (u *uriBinding) BindUri(m map[string][]string, obj any) error {
+	if err := mapFormByTag(obj, m, "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -3,23 +3,46 @@ package binding
 import (
 	"errors"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/go-playground/locales"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
 
 type StructValidator interface {
 	ValidateStruct(obj any) error
 	Validator() any
+	// RegisterValidation adds a custom validation tag.
+	RegisterValidation(tag string, fn validator.Func) error
+	// RegisterStructValidation adds a rule run on every value of the given
+	// struct types, for checks that span several fields.
+	RegisterStructValidation(fn validator.StructLevelFunc, types ...any)
+	// RegisterLocale adds a language for error messages. register installs
+	// its messages for the built-in tags, e.g. the RegisterDefaultTranslations
+	// function of a validator/v10/translations package.
+	RegisterLocale(l locales.Translator, register func(*validator.Validate, ut.Translator) error) error
+	// RegisterTranslation sets the message of tag in locale. {0} is replaced
+	// with the field name and {1} with the tag param.
+	RegisterTranslation(locale, tag, text string) error
 }
 
 var Validator StructValidator = &defaultValidator{}
 
+// defaultValidator checks `validate` tags and their `binding` shortcut,
+// e.g. `binding:"required"`. The shortcut is checked by a second validator
+// that shares every registration except struct-level rules.
 type defaultValidator struct {
-	once      sync.Once
-	validator *validator.Validate
+	once        sync.Once
+	validator   *validator.Validate
+	shortcut    *validator.Validate
+	translators map[*validator.Validate]*ut.UniversalTranslator
 }
 
+// ValidateStruct validates obj and returns ValidationErrors with messages
+// in DefaultLocale if any field fails.
 func (d *defaultValidator) ValidateStruct(obj any) error {
 	of := reflect.ValueOf(obj)
 	switch of.Kind() {
@@ -27,10 +50,18 @@ func (d *defaultValidator) ValidateStruct(obj any) error {
 		return d.ValidateStruct(of.Elem().Interface())
 	case reflect.Struct:
 		d.lazyInit()
-		return d.validator.Struct(obj)
+		err := d.validate(d.validator, obj)
+		if err != nil && !isValidationErrors(err) {
+			return err
+		}
+		return mergeValidationErrors(err, d.validate(d.shortcut, obj))
 	case reflect.Slice:
 		for i := 0; i < of.Len(); i++ {
 			if err := d.ValidateStruct(of.Index(i).Interface()); err != nil {
+				var errs ValidationErrors
+				if errors.As(err, &errs) {
+					return errs.withPrefix("[" + strconv.Itoa(i) + "]")
+				}
 				return err
 			}
 		}
@@ -45,8 +76,74 @@ func (d *defaultValidator) Validator() any {
 	return d.validator
 }
 
+func (d *defaultValidator) RegisterValidation(tag string, fn validator.Func) error {
+	d.lazyInit()
+	for _, v := range d.validators() {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *defaultValidator) RegisterStructValidation(fn validator.StructLevelFunc, types ...any) {
+	d.lazyInit()
+	d.validator.RegisterStructValidation(fn, types...)
+}
+
+func (d *defaultValidator) RegisterLocale(l locales.Translator, register func(*validator.Validate, ut.Translator) error) error {
+	d.lazyInit()
+	for _, v := range d.validators() {
+		if err := registerLocale(v, d.translators[v], l, register); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *defaultValidator) RegisterTranslation(locale, tag, text string) error {
+	d.lazyInit()
+	for _, v := range d.validators() {
+		if err := registerTranslation(v, d.translators[v], locale, tag, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *defaultValidator) validate(v *validator.Validate, obj any) error {
+	return toValidationErrors(v.Struct(obj), d.translators[v])
+}
+
+func (d *defaultValidator) validators() []*validator.Validate {
+	return []*validator.Validate{d.validator, d.shortcut}
+}
+
 func (d *defaultValidator) lazyInit() {
 	d.once.Do(func() {
 		d.validator = validator.New()
+		d.shortcut = validator.New()
+		d.shortcut.SetTagName("binding")
+		d.translators = make(map[*validator.Validate]*ut.UniversalTranslator, 2)
+		for _, v := range d.validators() {
+			v.RegisterTagNameFunc(fieldName)
+			registerFileValidations(v)
+			d.translators[v] = newTranslator(v)
+		}
 	})
 }
+
+// fieldName names fields in errors after their json tag, falling back to
+// the form tag and then the Go name, so error paths match the request.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,110 @@
+package binding
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/validator/v10"
+	frtranslations "github.com/go-playground/validator/v10/translations/fr"
+)
+
+type signupItem struct {
+	SKU string `json:"sku" validate:"required"`
+}
+
+type signup struct {
+	Email    string       `json:"email" validate:"required,email"`
+	Password string       `json:"password" validate:"min=8"`
+	Confirm  string       `json:"confirm"`
+	Nickname string       `form:"nick" validate:"nickname"`
+	Items    []signupItem `json:"items" validate:"dive"`
+}
+
+func newTestValidator(t *testing.T) StructValidator {
+	v := &defaultValidator{}
+	if err := v.RegisterValidation("nickname", func(fl validator.FieldLevel) bool {
+		return !strings.Contains(fl.Field().String(), " ")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.RegisterTranslation("en", "nickname", "{0} must not contain spaces"); err != nil {
+		t.Fatal(err)
+	}
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		s := sl.Current().Interface().(signup)
+		if s.Password != s.Confirm {
+			sl.ReportError(s.Confirm, "confirm", "Confirm", "eqfield", "password")
+		}
+	}, signup{})
+	return v
+}
+
+func TestValidationErrors(t *testing.T) {
+	v := newTestValidator(t)
+	err := v.ValidateStruct(&signup{
+		Email:    "bob",
+		Password: "secret",
+		Confirm:  "other",
+		Nickname: "bob smith",
+		Items:    []signupItem{{SKU: "a"}, {}},
+	})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %T %v", err, err)
+	}
+	want := map[string]string{
+		"email":        "email must be a valid email address",
+		"password":     "password must be at least 8 characters in length",
+		"nick":         "nick must not contain spaces",
+		"items[1].sku": "sku is a required field",
+		"confirm":      "confirm must be equal to password",
+	}
+	got := errs.Map()
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("%s: expected %q, got %q", field, msg, got[field])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d errors, got %v", len(want), got)
+	}
+	for _, e := range errs {
+		if e.Field == "password" && (e.Tag != "min" || e.Param != "8") {
+			t.Errorf("Unexpected tag and param %+v", e)
+		}
+	}
+}
+
+func TestValidationErrorsTranslate(t *testing.T) {
+	v := newTestValidator(t)
+	if err := v.RegisterLocale(fr.New(), frtranslations.RegisterDefaultTranslations); err != nil {
+		t.Fatal(err)
+	}
+	var errs ValidationErrors
+	if !errors.As(v.ValidateStruct(signupItem{}), &errs) {
+		t.Fatal("Expected ValidationErrors")
+	}
+	tests := map[string][]string{
+		"sku为必填字段":                     {"zh-CN"},
+		"sku est un champ obligatoire": {"de", "fr"},
+		"sku is a required field":      {"de"},
+	}
+	for want, locales := range tests {
+		if got := errs.Translate(locales...)[0].Message; got != want {
+			t.Errorf("%v: expected %q, got %q", locales, want, got)
+		}
+	}
+	if errs[0].Message != "sku is a required field" {
+		t.Errorf("Expected Translate not to modify the errors, got %q", errs[0].Message)
+	}
+}
+
+func TestValidationErrorsSlicePath(t *testing.T) {
+	var errs ValidationErrors
+	err := (&defaultValidator{}).ValidateStruct([]signupItem{{SKU: "a"}, {}})
+	if !errors.As(err, &errs) || errs[0].Field != "[1].sku" {
+		t.Errorf("Expected an error for [1].sku, got %v", err)
+	}
+}
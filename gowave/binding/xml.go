@@ -16,6 +16,9 @@ func (x xmlBinding) Bind(r *http.Request, obj any) error {
 	if r.Body == nil {
 		return nil
 	}
+	if err := setDefaults(obj); err != nil {
+		return err
+	}
 	if err := xml.NewDecoder(r.Body).Decode(obj); err != nil {
 		return err
 	}
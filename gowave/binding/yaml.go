@@ -0,0 +1,28 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+type yamlBinding struct {
+}
+
+func (y *yamlBinding) Name() string {
+	return "yaml"
+}
+
+func (y *yamlBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return errors.New("request body is nil")
+	}
+	if err := setDefaults(obj); err != nil {
+		return err
+	}
+	if err := yaml.NewDecoder(r.Body).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
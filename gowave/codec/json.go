@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONCodec is the JSON implementation used by the JSON bindings and
+// renderers. Assign JSON to swap in a faster encoder.
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	MarshalIndent(v any, prefix, indent string) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	NewEncoder(w io.Writer) JSONEncoder
+	NewDecoder(r io.Reader) JSONDecoder
+}
+
+type JSONEncoder interface {
+	SetEscapeHTML(on bool)
+	SetIndent(prefix, indent string)
+	Encode(v any) error
+}
+
+type JSONDecoder interface {
+	DisallowUnknownFields()
+	UseNumber()
+	Decode(v any) error
+}
+
+var JSON JSONCodec = StdJSON{}
+
+// StdJSON is the encoding/json codec.
+type StdJSON struct{}
+
+func (StdJSON) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdJSON) MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+func (StdJSON) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (StdJSON) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+func (StdJSON) NewDecoder(r io.Reader) JSONDecoder {
+	return json.NewDecoder(r)
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	gwlog "github.com/ChenGuo505/gowave/log"
 	"gopkg.in/yaml.v3"
@@ -22,6 +24,21 @@ type HttpConfig struct {
 	Name string `yaml:"name"`
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+	// Listen replaces host:port with one or more addresses; "unix:/path"
+	// listens on a Unix domain socket.
+	Listen            []string      `yaml:"listen"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+	MaxHeaderBytes    int           `yaml:"maxHeaderBytes"`
+	TLS               TLSConfig     `yaml:"tls"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	Reload   bool   `yaml:"reload"` // reload the cert and key when the files change
 }
 
 type RpcConfig struct {
@@ -58,14 +75,19 @@ func init() {
 	loadConfig()
 }
 
+const defaultConfFile = "config/gowave.yaml"
+
 func loadConfig() {
-	confFile := flag.String("conf", "config/gowave.yaml", "config file path")
-	flag.Parse()
-	if _, err := os.Stat(*confFile); err != nil {
+	// registered so that a flag.Parse in the host program accepts -conf
+	if flag.Lookup("conf") == nil {
+		flag.String("conf", defaultConfFile, "config file path")
+	}
+	confFile := confPath(os.Args[1:])
+	if _, err := os.Stat(confFile); err != nil {
 		gwlog.DefaultLogger().Info("config file not found, using default")
 		return
 	}
-	conf, err := os.ReadFile(*confFile)
+	conf, err := os.ReadFile(confFile)
 	if err != nil {
 		gwlog.DefaultLogger().Info("config file read error")
 		return
@@ -75,3 +97,28 @@ func loadConfig() {
 		return
 	}
 }
+
+// confPath picks -conf out of args without parsing the rest of the command
+// line, which belongs to the host program (or to go test).
+func confPath(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "conf" {
+			continue
+		}
+		if ok {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return defaultConfFile
+}
@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestConfPath(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, defaultConfFile},
+		{[]string{"-conf", "a.yaml"}, "a.yaml"},
+		{[]string{"--conf=b.yaml"}, "b.yaml"},
+		{[]string{"-test.v=true", "-port", "80", "-conf=c.yaml"}, "c.yaml"},
+		{[]string{"--", "-conf", "d.yaml"}, defaultConfFile},
+		{[]string{"-conf"}, defaultConfFile},
+	}
+	for _, tt := range tests {
+		if got := confPath(tt.args); got != tt.want {
+			t.Errorf("confPath(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package gowave
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// ParamConstraint reports whether a path segment may be bound to a
+// {name:constraint} route parameter.
+type ParamConstraint func(seg string) bool
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = map[string]ParamConstraint{
+		"int":   isInt,
+		"uint":  isUint,
+		"alpha": isAlpha,
+		"alnum": isAlnum,
+		"uuid":  isUUID,
+	}
+)
+
+// RegisterConstraint makes name usable as a typed constraint, e.g.
+// RegisterConstraint("slug", f) enables "/posts/{title:slug}".
+func RegisterConstraint(name string, constraint ParamConstraint) {
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+	constraints[name] = constraint
+}
+
+// compileConstraint resolves a typed constraint by name, otherwise treats
+// the constraint as a regular expression that must match the whole segment.
+func compileConstraint(constraint string) (ParamConstraint, error) {
+	constraintsMu.RLock()
+	c, ok := constraints[constraint]
+	constraintsMu.RUnlock()
+	if ok {
+		return c, nil
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint %q: %w", constraint, err)
+	}
+	return re.MatchString, nil
+}
+
+func isInt(s string) bool {
+	if len(s) > 1 && s[0] == '-' {
+		s = s[1:]
+	}
+	return isUint(s)
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i] | 0x20
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isUint(s[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
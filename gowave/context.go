@@ -1,16 +1,18 @@
 package gowave
 
 import (
+	"bytes"
 	"errors"
 	"html/template"
 	"io"
-	"log"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ChenGuo505/gowave/binding"
 	gwlog "github.com/ChenGuo505/gowave/log"
@@ -19,20 +21,31 @@ import (
 
 const (
 	defaultMaxMemory = 32 << 20 // 32 MB
+	abortIndex       = math.MaxInt / 2
 )
 
 type Context struct {
 	W   http.ResponseWriter
 	Req *http.Request
 
+	writer responseWriter
+
 	engine     *Engine
+	handlers   []HandlerFunc
+	index      int
+	params     Params
 	queryCache url.Values
 	formCache  url.Values
+	formErr    error
 
 	DisallowUnknownFields bool // Disallow unknown fields in JSON parsing
 	EnableJsonValidation  bool // Enable JSON validation
 
-	StatusCode int // HTTP status code for the response
+	// StatusCode mirrors Writer().Status().
+	//
+	// Deprecated: use Writer().Status(), which also sees statuses written
+	// by wrapped writers.
+	StatusCode int
 
 	Logger *gwlog.Logger
 
@@ -41,6 +54,53 @@ type Context struct {
 	sameSite http.SameSite
 }
 
+// Writer returns the writer that tracks the response, even if a middleware
+// has wrapped W.
+func (c *Context) Writer() ResponseWriter {
+	return &c.writer
+}
+
+func (c *Context) reset() {
+	c.handlers = nil
+	c.index = -1
+	c.params = c.params[:0]
+	c.queryCache = nil
+	c.formCache = nil
+	c.formErr = nil
+	c.Keys = nil
+	c.sameSite = 0
+}
+
+// Next runs the remaining handlers of the chain. Middlewares call it to run
+// code after the handlers that follow them.
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort stops the remaining handlers of the chain from running. The
+// current handler still runs to completion.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+func (c *Context) AbortWithStatusJSON(code int, data any) error {
+	c.Abort()
+	return c.JSON(code, data)
+}
+
 func (c *Context) initQueryCache() {
 	if c.Req != nil {
 		c.queryCache = c.Req.URL.Query()
@@ -68,6 +128,53 @@ func (c *Context) Get(key string) (any, bool) {
 	return value, ok
 }
 
+// Deadline, Done, Err and Value make Context a context.Context that
+// follows Req.Context(), so it can be passed to calls that should stop
+// when the request does. Value also returns the Keys set on the Context.
+// A Context is reused after its request; don't keep it beyond the handler.
+func (c *Context) Deadline() (time.Time, bool) {
+	if c.Req == nil {
+		return time.Time{}, false
+	}
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Err()
+}
+
+func (c *Context) Value(key any) any {
+	if k, ok := key.(string); ok {
+		if value, ok := c.Get(k); ok {
+			return value
+		}
+	}
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Value(key)
+}
+
+// Param returns the value bound to a route parameter, e.g. "id" for "/user/:id".
+// Wildcard segments are bound to "*" and catch-all segments to "**".
+func (c *Context) Param(name string) string {
+	return c.params.ByName(name)
+}
+
+func (c *Context) Params() Params {
+	return c.params
+}
+
 func (c *Context) SetBasicAuth(username, password string) {
 	c.Req.Header.Set("Authorization", "Basic "+BasicAuth(username, password))
 }
@@ -123,88 +230,90 @@ func (c *Context) GetHeader(key string) string {
 	return ""
 }
 
-func (c *Context) initFormCache() {
-	if c.Req != nil {
-		if err := c.Req.ParseMultipartForm(defaultMaxMemory); err != nil {
-			if errors.Is(err, http.ErrNotMultipart) {
-				log.Fatal(err)
-			}
-		}
+// initFormCache parses the request body once. A body that is not
+// multipart is parsed as urlencoded; malformed bodies are reported to
+// every form getter instead of being treated as empty.
+func (c *Context) initFormCache() error {
+	if c.formCache != nil {
+		return c.formErr
+	}
+	c.formCache = make(url.Values)
+	if c.Req == nil {
+		return nil
+	}
+	// ParseMultipartForm hides ParseForm errors behind ErrNotMultipart.
+	c.formErr = c.Req.ParseForm()
+	if err := c.Req.ParseMultipartForm(defaultMaxMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		c.formErr = err
+	}
+	if c.Req.PostForm != nil {
 		c.formCache = c.Req.PostForm
-	} else {
-		c.formCache = make(url.Values)
 	}
+	return c.formErr
 }
 
-func (c *Context) GetForm(key string) string {
-	c.initFormCache()
-	return c.formCache.Get(key)
+func (c *Context) GetForm(key string) (string, error) {
+	err := c.initFormCache()
+	return c.formCache.Get(key), err
 }
 
-func (c *Context) GetFormAll(key string) []string {
-	c.initFormCache()
-	values, _ := c.formCache[key]
-	return values
+func (c *Context) GetFormAll(key string) ([]string, error) {
+	err := c.initFormCache()
+	return c.formCache[key], err
 }
 
-func (c *Context) GetFormDefault(key, defaultValue string) string {
-	values := c.GetFormAll(key)
+func (c *Context) GetFormDefault(key, defaultValue string) (string, error) {
+	values, err := c.GetFormAll(key)
 	if len(values) == 0 {
-		return defaultValue
+		return defaultValue, err
 	}
-	return values[0]
+	return values[0], err
 }
 
-func (c *Context) GetFormMap(key string) map[string]string {
-	c.initFormCache()
-	return c.getFromMap(c.formCache, key)
+func (c *Context) GetFormMap(key string) (map[string]string, error) {
+	err := c.initFormCache()
+	return c.getFromMap(c.formCache, key), err
 }
 
-func (c *Context) FormFile(name string) *multipart.FileHeader {
-	file, header, err := c.Req.FormFile(name)
+// FormFile returns the first file uploaded under name. It returns
+// http.ErrNotMultipart for non-multipart requests and http.ErrMissingFile
+// if no file was sent under name.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	files, err := c.FormFiles(name)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer func(file multipart.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(file)
-	return header
+	return files[0], nil
 }
 
-func (c *Context) FormFiles(name string) []*multipart.FileHeader {
-	multipartForm, err := c.MultipartForm()
+func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
 	if err != nil {
-		log.Println(err)
-		return make([]*multipart.FileHeader, 0)
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
 	}
-	return multipartForm.File[name]
+	return files, nil
 }
 
-func (c *Context) SaveFile(file *multipart.FileHeader, dst string) error {
+func (c *Context) SaveFile(file *multipart.FileHeader, dst string) (err error) {
 	src, err := file.Open()
 	if err != nil {
 		return err
 	}
-	defer func(src multipart.File) {
-		err := src.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(src)
+	defer src.Close()
 
 	out, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
-	defer func(out *os.File) {
-		err := out.Close()
-		if err != nil {
-			log.Fatal(err)
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
 		}
-	}(out)
+	}()
 
 	_, err = io.Copy(out, src)
 	return err
@@ -228,16 +337,82 @@ func (c *Context) getFromMap(cache map[string][]string, key string) map[string]s
 }
 
 func (c *Context) BindJson(obj any) error {
-	json := binding.JSON
+	// a copy, so that the options of one request don't leak into others
+	json := *binding.JSON
 	json.DisallowUnknownFields = c.DisallowUnknownFields
 	json.EnableJsonValidation = c.EnableJsonValidation
-	return c.mustBindWith(json, obj)
+	return c.mustBindWith(&json, obj)
 }
 
 func (c *Context) BindXml(obj any) error {
 	return c.mustBindWith(binding.XML, obj)
 }
 
+func (c *Context) BindYAML(obj any) error {
+	return c.mustBindWith(binding.YAML, obj)
+}
+
+func (c *Context) BindTOML(obj any) error {
+	return c.mustBindWith(binding.TOML, obj)
+}
+
+func (c *Context) BindMsgPack(obj any) error {
+	return c.mustBindWith(binding.MsgPack, obj)
+}
+
+func (c *Context) BindProtoBuf(obj any) error {
+	return c.mustBindWith(binding.ProtoBuf, obj)
+}
+
+func (c *Context) BindQuery(obj any) error {
+	return c.mustBindWith(binding.Query, obj)
+}
+
+func (c *Context) BindForm(obj any) error {
+	return c.mustBindWith(binding.Form, obj)
+}
+
+// BindMultipart binds form values and uploaded files of a multipart body.
+func (c *Context) BindMultipart(obj any) error {
+	return c.mustBindWith(binding.FormMultipart, obj)
+}
+
+func (c *Context) BindHeader(obj any) error {
+	return c.mustBindWith(binding.Header, obj)
+}
+
+// BindURI binds the route params into obj using `uri` struct tags.
+func (c *Context) BindURI(obj any) error {
+	m := make(map[string][]string, len(c.params))
+	for _, p := range c.params {
+		m[p.Key] = []string{p.Value}
+	}
+	if err := binding.Uri.BindUri(m, obj); err != nil {
+		c.W.WriteHeader(http.StatusBadRequest)
+		return err
+	}
+	return nil
+}
+
+// Bind picks a binding from the request method and Content-Type: JSON,
+// XML, YAML, TOML, MsgPack and ProtoBuf bodies are decoded, multipart forms
+// bind values and files, and anything else, including every GET, binds
+// query and form values.
+func (c *Context) Bind(obj any) error {
+	b := binding.Default(c.Req.Method, c.ContentType())
+	if b == binding.Binding(binding.JSON) {
+		return c.BindJson(obj)
+	}
+	return c.mustBindWith(b, obj)
+}
+
+// ContentType returns the media type of the request's Content-Type header,
+// without parameters such as charset.
+func (c *Context) ContentType() string {
+	contentType, _, _ := strings.Cut(c.GetHeader("Content-Type"), ";")
+	return strings.TrimSpace(contentType)
+}
+
 func (c *Context) HTML(code int, html string) error {
 	return c.render(code, &render.HTML{Data: html, IsTemplate: false})
 }
@@ -281,18 +456,86 @@ func (c *Context) JSON(code int, data any) error {
 	return c.render(code, &render.JSON{Data: data})
 }
 
+func (c *Context) IndentedJSON(code int, data any) error {
+	return c.render(code, &render.IndentedJSON{Data: data})
+}
+
+// SecureJSON prefixes array responses with Engine.SecureJSONPrefix.
+func (c *Context) SecureJSON(code int, data any) error {
+	return c.render(code, &render.SecureJSON{Prefix: c.engine.SecureJSONPrefix, Data: data})
+}
+
+// JSONP wraps the JSON in a call to the function named by the callback
+// query parameter. A callback that is not a dotted JavaScript identifier is
+// answered with 400 Bad Request and render.ErrInvalidCallback.
+func (c *Context) JSONP(code int, data any) error {
+	callback := c.GetQuery("callback")
+	if callback != "" && !render.ValidCallback(callback) {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return render.ErrInvalidCallback
+	}
+	return c.render(code, &render.JSONP{Callback: callback, Data: data})
+}
+
+func (c *Context) AsciiJSON(code int, data any) error {
+	return c.render(code, &render.AsciiJSON{Data: data})
+}
+
+// PureJSON does not escape HTML characters, unlike JSON.
+func (c *Context) PureJSON(code int, data any) error {
+	return c.render(code, &render.PureJSON{Data: data})
+}
+
+// StreamJSON writes data, a slice, an array or an iter.Seq[any], as a JSON
+// array element by element instead of marshalling it at once. Data of
+// another type is an error returned before anything is sent; an element
+// that fails to marshal can only cut the array short.
+func (c *Context) StreamJSON(code int, data any) error {
+	r := &render.StreamJSON{Data: data}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	r.SetContentType(c.W)
+	c.W.WriteHeader(code)
+	return r.Render(c.W)
+}
+
 func (c *Context) XML(code int, data any) error {
 	return c.render(code, &render.XML{Data: data})
 }
 
+func (c *Context) YAML(code int, data any) error {
+	return c.render(code, &render.YAML{Data: data})
+}
+
+func (c *Context) TOML(code int, data any) error {
+	return c.render(code, &render.TOML{Data: data})
+}
+
+func (c *Context) MsgPack(code int, data any) error {
+	return c.render(code, &render.MsgPack{Data: data})
+}
+
+// ProtoBuf writes data, which must be a proto.Message, in wire format.
+func (c *Context) ProtoBuf(code int, data any) error {
+	return c.render(code, &render.ProtoBuf{Data: data})
+}
+
+// Redirect replies with a redirect to url. It bypasses render, which sends
+// the status before rendering, because Location has to be set first.
 func (c *Context) Redirect(code int, url string) error {
-	return c.render(code, &render.Redirect{Code: code, Req: c.Req, URL: url})
+	return (&render.Redirect{Code: code, Req: c.Req, URL: url}).Render(c.W)
 }
 
 func (c *Context) String(code int, format string, args ...any) error {
 	return c.render(code, &render.String{Format: format, Data: args})
 }
 
+// Status writes the response status code without a body.
+func (c *Context) Status(code int) {
+	c.W.WriteHeader(code)
+}
+
 func (c *Context) File(filename string) {
 	http.ServeFile(c.W, c.Req, filename)
 }
@@ -318,16 +561,39 @@ func (c *Context) Fail(code int, msg string) {
 	_ = c.String(code, msg)
 }
 
-func (c *Context) render(code int, render render.Render) error {
+// render renders into a buffer before sending anything, so that a render
+// error is returned while the handler can still choose the response.
+func (c *Context) render(code int, r render.Render) error {
+	buf := &renderBuffer{ResponseWriter: c.W}
+	if err := r.Render(buf); err != nil {
+		return err
+	}
+	// headers set after WriteHeader are not sent
+	r.SetContentType(c.W)
 	c.W.WriteHeader(code)
-	c.StatusCode = code
-	render.SetContentType(c.W)
-	err := render.Render(c.W)
+	_, err := c.W.Write(buf.Bytes())
 	return err
 }
 
+// renderBuffer collects the body written by a render.Render.
+type renderBuffer struct {
+	http.ResponseWriter
+	bytes.Buffer
+}
+
+func (b *renderBuffer) Write(p []byte) (int, error) {
+	return b.Buffer.Write(p)
+}
+
+func (b *renderBuffer) WriteHeader(int) {}
+
 func (c *Context) mustBindWith(j binding.Binding, obj any) error {
 	if err := c.shouldBind(j, obj); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.W.WriteHeader(http.StatusRequestEntityTooLarge)
+			return err
+		}
 		c.W.WriteHeader(http.StatusBadRequest)
 		return err
 	}
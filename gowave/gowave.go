@@ -6,128 +6,278 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ChenGuo505/gowave/config"
 	"github.com/ChenGuo505/gowave/gateway"
 	gwlog "github.com/ChenGuo505/gowave/log"
 	"github.com/ChenGuo505/gowave/register"
 	"github.com/ChenGuo505/gowave/render"
+	"github.com/ChenGuo505/gowave/websocket"
 )
 
+// defaultParamsCap is the initial params capacity of a pooled Context.
+const defaultParamsCap = 8
+
 type HandlerFunc func(ctx *Context)
 
+// MiddlewareFunc wraps the rest of the handler chain as next. It is adapted
+// to the chain when registered: calling next runs the remaining handlers,
+// returning without calling it aborts the chain.
+//
+// Middlewares run in the order they are added, so the first one is the
+// outermost. This reverses the earlier nesting, where each middleware
+// wrapped the ones added before it and the last one added ran first.
 type MiddlewareFunc func(next HandlerFunc) HandlerFunc
 
+func wrapMiddleware(middleware MiddlewareFunc) HandlerFunc {
+	return func(ctx *Context) {
+		called := false
+		middleware(func(ctx *Context) {
+			called = true
+			ctx.Next()
+		})(ctx)
+		if !called {
+			ctx.Abort()
+		}
+	}
+}
+
+func wrapMiddlewares(middlewares []MiddlewareFunc) []HandlerFunc {
+	handlers := make([]HandlerFunc, 0, len(middlewares))
+	for _, middleware := range middlewares {
+		handlers = append(handlers, wrapMiddleware(middleware))
+	}
+	return handlers
+}
+
+func combineHandlers(handlers []HandlerFunc, more ...HandlerFunc) []HandlerFunc {
+	merged := make([]HandlerFunc, 0, len(handlers)+len(more))
+	merged = append(merged, handlers...)
+	return append(merged, more...)
+}
+
 type routerGroup struct {
-	prefix      string
-	routes      map[string]map[string]HandlerFunc
-	middlewares []MiddlewareFunc
-	trie        *Trie
-	logger      *gwlog.Logger
+	prefix   string
+	handlers []HandlerFunc // the group's own middlewares, without the parent's
+	parent   *routerGroup
+	router   *router
+	logger   *gwlog.Logger
 }
 
+// Use appends middlewares to the group. They run in the order added, the
+// first one outermost, after those of the parent groups, and also apply to
+// routes already registered on the group or its sub-groups.
 func (g *routerGroup) Use(middlewares ...MiddlewareFunc) {
-	g.middlewares = append(g.middlewares, middlewares...)
+	g.UseHandlers(wrapMiddlewares(middlewares)...)
 }
 
-func (g *routerGroup) Handle(h HandlerFunc, ctx *Context) {
-	if g.middlewares != nil {
-		for _, middleware := range g.middlewares {
-			h = middleware(h)
+// UseHandlers appends chain-style middlewares that call ctx.Next to run the
+// rest of the chain and ctx.Abort to stop it.
+func (g *routerGroup) UseHandlers(handlers ...HandlerFunc) {
+	g.handlers = append(g.handlers, handlers...)
+	g.router.rebuild(g)
+}
+
+// Group creates a sub-group whose prefix is appended to g's prefix. Routes
+// of the sub-group run g's middlewares, including ones added later, before
+// its own.
+func (g *routerGroup) Group(prefix string) *routerGroup {
+	return &routerGroup{
+		prefix: joinPaths(g.prefix, prefix),
+		parent: g,
+		router: g.router,
+		logger: g.logger,
+	}
+}
+
+// chain returns the middlewares of g and its parents, outermost first.
+func (g *routerGroup) chain() []HandlerFunc {
+	if g.parent == nil {
+		return combineHandlers(g.handlers)
+	}
+	return combineHandlers(g.parent.chain(), g.handlers...)
+}
+
+// contains reports whether sub is g or one of its sub-groups.
+func (g *routerGroup) contains(sub *routerGroup) bool {
+	for ; sub != nil; sub = sub.parent {
+		if sub == g {
+			return true
 		}
 	}
-	h(ctx)
+	return false
+}
+
+// Handle runs h behind the group's middlewares. Called from a handler, it
+// runs as a nested chain and leaves the caller's chain to continue, unless
+// the nested chain was aborted.
+func (g *routerGroup) Handle(h HandlerFunc, ctx *Context) {
+	handlers, index := ctx.handlers, ctx.index
+	ctx.handlers = combineHandlers(g.chain(), h)
+	ctx.index = -1
+	ctx.Next()
+	aborted := ctx.IsAborted()
+	ctx.handlers, ctx.index = handlers, index
+	if aborted {
+		ctx.Abort()
+	}
 }
 
-func (g *routerGroup) register(path string, handler HandlerFunc, method string, middlewares ...MiddlewareFunc) {
-	_, ok := g.routes[path]
-	if !ok {
-		g.routes[path] = make(map[string]HandlerFunc)
+func (g *routerGroup) register(path string, handler HandlerFunc, method string, middlewares ...MiddlewareFunc) *Route {
+	path = joinPaths(g.prefix, path)
+	r := &Route{Method: method, Path: path, router: g.router}
+	root := g.router.trees.get(method)
+	if root == nil {
+		root = &treeNode{}
+		g.router.trees = append(g.router.trees, methodTree{method: method, root: root})
 	}
-	_, ok = g.routes[path][method]
-	if ok {
+	node, err := root.addRoute(path)
+	if err != nil {
+		g.logger.Error(err.Error())
+		return r
+	}
+	rt := &route{group: g, own: combineHandlers(wrapMiddlewares(middlewares), handler)}
+	rt.handlers = combineHandlers(g.chain(), rt.own...)
+	if node.route != nil {
 		//log.Fatalf("duplicate handler for %s, method: %s", path, method)
 		g.logger.Error(fmt.Sprintf("duplicate handler for %s, method: %s", path, method))
+		g.router.routes = slices.DeleteFunc(g.router.routes, func(old *route) bool {
+			return old == node.route
+		})
 	}
-	for _, middleware := range middlewares {
-		handler = middleware(handler)
+	node.route = rt
+	g.router.routes = append(g.router.routes, rt)
+	r.registered = true
+	return r
+}
+
+// Any registers handler for every standard method. The returned Route is
+// the GET route; naming it names the routes of all the methods.
+func (g *routerGroup) Any(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	r := g.register(path, handler, http.MethodGet, middlewares...)
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodPatch, http.MethodHead} {
+		r.also = append(r.also, g.register(path, handler, method, middlewares...))
 	}
-	g.routes[path][method] = handler
-	g.trie.Put(path, "")
+	return r
 }
 
-func (g *routerGroup) Any(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodGet, middlewares...)
-	g.register(path, handler, http.MethodPost, middlewares...)
-	g.register(path, handler, http.MethodPut, middlewares...)
-	g.register(path, handler, http.MethodDelete, middlewares...)
-	g.register(path, handler, http.MethodOptions, middlewares...)
-	g.register(path, handler, http.MethodPatch, middlewares...)
-	g.register(path, handler, http.MethodHead, middlewares...)
+func (g *routerGroup) Get(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodGet, middlewares...)
 }
 
-func (g *routerGroup) Get(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodGet, middlewares...)
+func (g *routerGroup) Post(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodPost, middlewares...)
 }
 
-func (g *routerGroup) Post(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodPost, middlewares...)
+func (g *routerGroup) Put(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodPut, middlewares...)
 }
 
-func (g *routerGroup) Put(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodPut, middlewares...)
+func (g *routerGroup) Delete(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodDelete, middlewares...)
 }
 
-func (g *routerGroup) Delete(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodDelete, middlewares...)
+func (g *routerGroup) Options(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodOptions, middlewares...)
 }
 
-func (g *routerGroup) Options(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodOptions, middlewares...)
+func (g *routerGroup) Patch(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodPatch, middlewares...)
 }
 
-func (g *routerGroup) Patch(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodPatch, middlewares...)
+func (g *routerGroup) Head(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) *Route {
+	return g.register(path, handler, http.MethodHead, middlewares...)
 }
 
-func (g *routerGroup) Head(path string, handler HandlerFunc, middlewares ...MiddlewareFunc) {
-	g.register(path, handler, http.MethodHead, middlewares...)
+type route struct {
+	group    *routerGroup
+	own      []HandlerFunc // per-route middlewares followed by the handler
+	handlers []HandlerFunc // group middlewares followed by own
 }
 
 type router struct {
-	routerGroups []*routerGroup
-	engine       *Engine
+	trees  methodTrees
+	routes []*route
+	names  map[string][]routeKey // route name -> methods of one path pattern
+	engine *Engine
+}
+
+// rebuild recomputes the handler chains of the routes registered on g or its
+// sub-groups after g's middlewares changed.
+func (r *router) rebuild(g *routerGroup) {
+	for _, rt := range r.routes {
+		if g.contains(rt.group) {
+			rt.handlers = combineHandlers(rt.group.chain(), rt.own...)
+		}
+	}
 }
 
 func (r *router) Group(prefix string) *routerGroup {
 	routerGroup := &routerGroup{
-		prefix: prefix,
-		routes: make(map[string]map[string]HandlerFunc),
-		trie:   NewTrie(),
+		prefix: joinPaths("", prefix),
+		router: r,
 		logger: r.engine.Logger,
 	}
 	routerGroup.Use(r.engine.middlewares...)
-	r.routerGroups = append(r.routerGroups, routerGroup)
 	return routerGroup
 }
 
 type Engine struct {
 	router
-	HTMLRender       render.HTMLRender
-	Logger           *gwlog.Logger
-	GatewayOn        bool
+	HTMLRender render.HTMLRender
+	Logger     *gwlog.Logger
+	GatewayOn  bool
+
+	// HandleOPTIONS answers OPTIONS requests for registered paths with the
+	// allowed methods unless an OPTIONS route is registered. On by default.
+	HandleOPTIONS bool
+	// RedirectTrailingSlash redirects /foo/ to /foo, or /foo to /foo/, when
+	// only the other form is registered.
+	RedirectTrailingSlash bool
+	// RedirectFixedPath cleans the path (// and ../ elements) and redirects
+	// to a case-insensitive match of a registered path.
+	RedirectFixedPath bool
+	// HttpConfig holds the listen addresses, timeouts and TLS files used by
+	// Run and RunWithTLS. It starts as a copy of config.RootConfig.Http.
+	HttpConfig config.HttpConfig
+	// ShutdownTimeout bounds how long Run waits for in-flight requests after
+	// SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration
+	// Upgrader performs the WebSocket handshakes of Context.Upgrade.
+	Upgrader websocket.Upgrader
+	// SecureJSONPrefix is prepended to Context.SecureJSON array responses.
+	SecureJSONPrefix string
+
 	funcMap          template.FuncMap
 	middlewares      []MiddlewareFunc
+	noRoute          []HandlerFunc
+	noMethod         []HandlerFunc
 	gatewayTrie      *Trie
 	gatewayConfigMap map[string]gateway.Config
 	register         register.Register
 	pool             sync.Pool
+
+	mu           sync.Mutex
+	server       *http.Server
+	onStart      []HookFunc
+	onShutdown   []HookFunc
+	shutdownOnce sync.Once
+	shutdownErr  error
+	done         chan struct{}
 }
 
 func New() *Engine {
 	engine := &Engine{
-		router:           router{},
+		router:           router{names: make(map[string][]routeKey)},
+		HandleOPTIONS:    true,
+		HttpConfig:       config.RootConfig.Http,
+		ShutdownTimeout:  defaultShutdownTimeout,
+		SecureJSONPrefix: render.DefaultSecureJSONPrefix,
+		done:             make(chan struct{}),
 		gatewayTrie:      NewTrie(),
 		gatewayConfigMap: make(map[string]gateway.Config),
 	}
@@ -151,7 +301,20 @@ func New() *Engine {
 }
 
 func (e *Engine) allocateContext() *Context {
-	return &Context{engine: e}
+	ctx := &Context{engine: e, params: make(Params, 0, defaultParamsCap)}
+	ctx.writer.mirror = &ctx.StatusCode
+	return ctx
+}
+
+// NoRoute sets the handlers run when no route matches the request path.
+func (e *Engine) NoRoute(handlers ...HandlerFunc) {
+	e.noRoute = handlers
+}
+
+// NoMethod sets the handlers run when the path matches a route registered
+// for other methods only. The Allow header is set before they run.
+func (e *Engine) NoMethod(handlers ...HandlerFunc) {
+	e.noMethod = handlers
 }
 
 func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
@@ -164,7 +327,10 @@ func (e *Engine) SetHTMLRender(template *template.Template) {
 
 func (e *Engine) SetGatewayConfigs(configs []gateway.Config) {
 	for _, conf := range configs {
-		e.gatewayTrie.Put(conf.Path, conf.Name)
+		if err := e.gatewayTrie.Put(conf.Path, conf.Name); err != nil {
+			e.Logger.Error(err.Error())
+			continue
+		}
 		e.gatewayConfigMap[conf.Name] = conf
 	}
 }
@@ -176,9 +342,11 @@ func (e *Engine) LoadTemplate(pattern string) {
 
 func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	ctx := e.pool.Get().(*Context)
-	ctx.W = w
+	ctx.writer.reset(w)
+	ctx.W = &ctx.writer
 	ctx.Req = req
 	ctx.Logger = e.Logger
+	ctx.reset()
 	e.handleRequest(ctx, w, req)
 	e.pool.Put(ctx)
 }
@@ -230,55 +398,155 @@ func (e *Engine) handleRequest(ctx *Context, w http.ResponseWriter, req *http.Re
 		return
 	}
 	e.Logger.Info(fmt.Sprintf("path: %s, method: %s", req.URL.Path, req.Method))
-	for _, group := range e.routerGroups {
-		routerName := TrimPrefix(req.URL.Path, "/"+group.prefix)
-		node := group.trie.Get(routerName)
-		if node != nil && node.isEnd {
-			handler, ok := group.routes[node.routerName][req.Method]
-			if ok {
-				group.Handle(handler, ctx)
-				return
-			}
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			_, err := fmt.Fprintf(w, "405 Method Not Allowed")
-			if err != nil {
+	path := req.URL.Path
+	if root := e.trees.get(req.Method); root != nil {
+		if node := root.lookup(path, &ctx.params); node != nil {
+			ctx.handlers = node.route.handlers
+			ctx.Next()
+			return
+		}
+	}
+	if req.Method == http.MethodHead {
+		if root := e.trees.get(http.MethodGet); root != nil {
+			if node := root.lookup(path, &ctx.params); node != nil {
+				ctx.writer.ResponseWriter = &headResponseWriter{ResponseWriter: w}
+				ctx.handlers = node.route.handlers
+				ctx.Next()
 				return
 			}
+		}
+	}
+	if req.Method != http.MethodConnect && path != "/" {
+		if location, ok := e.fixedPath(path, req.Method, ctx); ok {
+			e.redirect(ctx, location)
 			return
 		}
 	}
-	w.WriteHeader(http.StatusNotFound)
-	_, err := fmt.Fprintf(w, "404 Not Found")
-	if err != nil {
+	if allow := e.allowedMethods(path, req.Method, ctx); len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		if req.Method == http.MethodOptions && e.HandleOPTIONS {
+			e.handleWithMiddlewares(ctx, func(ctx *Context) {
+				ctx.Status(http.StatusNoContent)
+			})
+			return
+		}
+		e.handleError(ctx, http.StatusMethodNotAllowed, e.noMethod)
 		return
 	}
+	e.handleError(ctx, http.StatusNotFound, e.noRoute)
 }
 
-func (e *Engine) handler() http.Handler {
-	return e
+// fixedPath looks for the registered path that an unmatched request path
+// should redirect to, according to RedirectTrailingSlash and
+// RedirectFixedPath.
+func (e *Engine) fixedPath(path string, method string, ctx *Context) (string, bool) {
+	root := e.trees.get(method)
+	if root == nil && method == http.MethodHead {
+		root = e.trees.get(http.MethodGet)
+	}
+	if root == nil {
+		return "", false
+	}
+	defer func() {
+		ctx.params = ctx.params[:0]
+	}()
+	if e.RedirectTrailingSlash {
+		if alt := toggleTrailingSlash(path); root.lookup(alt, &ctx.params) != nil {
+			return alt, true
+		}
+	}
+	if e.RedirectFixedPath {
+		cleaned := cleanPath(path)
+		if fixed := root.lookupFold(cleaned, make([]byte, 0, len(cleaned)+1)); fixed != nil {
+			return string(fixed), true
+		}
+		if e.RedirectTrailingSlash {
+			alt := toggleTrailingSlash(cleaned)
+			if fixed := root.lookupFold(alt, make([]byte, 0, len(alt))); fixed != nil {
+				return string(fixed), true
+			}
+		}
+	}
+	return "", false
 }
 
-func (e *Engine) Run() {
-	http.Handle("/", e)
+// redirect permanently redirects to location, keeping the query string.
+// GET and HEAD use 301, other methods 308 so that the body is resent.
+func (e *Engine) redirect(ctx *Context, location string) {
+	code := http.StatusPermanentRedirect
+	if ctx.Req.Method == http.MethodGet || ctx.Req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	if ctx.Req.URL.RawQuery != "" {
+		location += "?" + ctx.Req.URL.RawQuery
+	}
+	e.handleWithMiddlewares(ctx, func(ctx *Context) {
+		_ = ctx.Redirect(code, location)
+	})
+}
 
-	port := config.RootConfig.Http.Port
-	if port == 0 {
-		port = 8080
+// allowedMethods lists the methods, other than method, that have a route
+// matching path. HEAD is implied by GET, and OPTIONS by any route while
+// HandleOPTIONS is on.
+func (e *Engine) allowedMethods(path string, method string, ctx *Context) []string {
+	var allow []string
+	for _, tree := range e.trees {
+		if tree.method == method {
+			continue
+		}
+		if tree.root.lookup(path, &ctx.params) != nil {
+			allow = append(allow, tree.method)
+		}
+		ctx.params = ctx.params[:0]
 	}
-	e.Logger.Info(fmt.Sprintf("Starting server on port :%d", port))
-	err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
-	if err != nil {
-		e.Logger.Fatal(fmt.Sprintf("Failed to start server: %v", err))
-		return
+	if len(allow) == 0 {
+		return nil
+	}
+	if slices.Contains(allow, http.MethodGet) && !slices.Contains(allow, http.MethodHead) {
+		allow = append(allow, http.MethodHead)
+	}
+	if e.HandleOPTIONS && !slices.Contains(allow, http.MethodOptions) {
+		allow = append(allow, http.MethodOptions)
 	}
+	return allow
 }
 
-func (e *Engine) RunWithTLS(addr, certFile, keyFile string) {
-	err := http.ListenAndServeTLS(addr, certFile, keyFile, e.handler())
-	if err != nil {
-		e.Logger.Fatal(fmt.Sprintf("Failed to start server with TLS: %v", err))
-		return
+// handleError runs handlers as a chain behind the engine middlewares and
+// falls back to a plain-text status response if none of them rendered
+// anything.
+func (e *Engine) handleError(ctx *Context, code int, handlers []HandlerFunc) {
+	fallback := func(ctx *Context) {
+		if !ctx.Writer().Written() {
+			_ = ctx.String(code, fmt.Sprintf("%d %s", code, http.StatusText(code)))
+		}
 	}
+	ctx.handlers = combineHandlers(combineHandlers(wrapMiddlewares(e.middlewares), handlers...), fallback)
+	ctx.Next()
+	// a handler that aborted the chain may not have reached the fallback
+	fallback(ctx)
+}
+
+func (e *Engine) handleWithMiddlewares(ctx *Context, h HandlerFunc) {
+	ctx.handlers = append(wrapMiddlewares(e.middlewares), h)
+	ctx.Next()
+}
+
+// headResponseWriter serves HEAD requests from GET handlers by dropping
+// the response body.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (e *Engine) handler() http.Handler {
+	return e
 }
 
 func (e *Engine) Register(service string, host string, port int) {
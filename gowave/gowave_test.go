@@ -0,0 +1,682 @@
+package gowave
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ChenGuo505/gowave/binding"
+	gwlog "github.com/ChenGuo505/gowave/log"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestEngine() *Engine {
+	DefaultWriter = io.Discard
+	engine := New()
+	engine.Logger.Level = gwlog.LoggerLevelError
+	return engine
+}
+
+func TestEngineParallelRequests(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/user/:id", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, ctx.Param("id"))
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				id := strconv.Itoa(i*1000 + j)
+				w := httptest.NewRecorder()
+				engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user/"+id, nil))
+				if w.Code != http.StatusOK || w.Body.String() != id {
+					t.Errorf("Expected 200 %s, got %d %s", id, w.Code, w.Body.String())
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEngineParamConstraints(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/orders/{id:int}", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "order "+ctx.Param("id"))
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders/7", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "order 7" {
+		t.Errorf("Expected 200 order 7, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders/abc", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+func TestEngineNestedGroups(t *testing.T) {
+	engine := newTestEngine()
+	var trace []string
+	mark := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx *Context) {
+				trace = append(trace, name)
+				next(ctx)
+			}
+		}
+	}
+	api := engine.Group("api")
+	api.Use(mark("api"))
+	v1 := api.Group("v1")
+	v1.Use(mark("v1"))
+	v1.Get("/users/:id", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "v1 "+ctx.Param("id"))
+	})
+	api.Get("/users/:id", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "api "+ctx.Param("id"))
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil))
+	if w.Body.String() != "v1 1" {
+		t.Errorf("Expected v1 1, got %s", w.Body.String())
+	}
+	if len(trace) != 2 || trace[0] != "api" || trace[1] != "v1" {
+		t.Errorf("Expected [api v1], got %v", trace)
+	}
+	trace = nil
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/2", nil))
+	if w.Body.String() != "api 2" {
+		t.Errorf("Expected api 2, got %s", w.Body.String())
+	}
+	if len(trace) != 1 || trace[0] != "api" {
+		t.Errorf("Expected [api], got %v", trace)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	engine := newTestEngine()
+	var trace []string
+	mark := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx *Context) {
+				trace = append(trace, name)
+				next(ctx)
+				trace = append(trace, name+" done")
+			}
+		}
+	}
+	g := engine.Group("api")
+	g.Use(mark("first"), mark("second"))
+	g.Use(mark("third"))
+	g.Get("/ping", func(ctx *Context) {
+		trace = append(trace, "handler")
+	}, mark("route first"), mark("route second"))
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/ping", nil))
+	expected := "first,second,third,route first,route second,handler," +
+		"route second done,route first done,third done,second done,first done"
+	if strings.Join(trace, ",") != expected {
+		t.Errorf("Expected %s, got %v", expected, trace)
+	}
+}
+
+func TestGroupUseAfterRegister(t *testing.T) {
+	engine := newTestEngine()
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(ctx *Context) {
+			trace = append(trace, name)
+		}
+	}
+	api := engine.Group("api")
+	v1 := api.Group("v1")
+	v1.Get("/ping", func(ctx *Context) {
+		trace = append(trace, "handler")
+	})
+	v1.UseHandlers(mark("v1"))
+	api.UseHandlers(mark("api"))
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil))
+	if strings.Join(trace, ",") != "api,v1,handler" {
+		t.Errorf("Expected api,v1,handler, got %v", trace)
+	}
+}
+
+func TestGroupHandleNested(t *testing.T) {
+	engine := newTestEngine()
+	var trace []string
+	admin := engine.Group("admin")
+	admin.UseHandlers(func(ctx *Context) {
+		trace = append(trace, "admin")
+	})
+	api := engine.Group("api")
+	api.UseHandlers(func(ctx *Context) {
+		trace = append(trace, "before")
+		ctx.Next()
+		trace = append(trace, "after")
+	})
+	api.Get("/proxy", func(ctx *Context) {
+		admin.Handle(func(ctx *Context) {
+			trace = append(trace, "nested")
+		}, ctx)
+		trace = append(trace, "handler")
+	})
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/proxy", nil))
+	if strings.Join(trace, ",") != "before,admin,nested,handler,after" {
+		t.Errorf("Expected before,admin,nested,handler,after, got %v", trace)
+	}
+}
+
+func TestEngineNoRouteNoMethod(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/users/:id", func(ctx *Context) {})
+	g.Delete("/users/:id", func(ctx *Context) {})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users/1", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "405 Method Not Allowed" {
+		t.Errorf("Expected 405, got %d %s", w.Code, w.Body.String())
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, DELETE, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow: GET, DELETE, HEAD, OPTIONS, got %s", allow)
+	}
+
+	engine.NoRoute(func(ctx *Context) {
+		_ = ctx.JSON(http.StatusNotFound, map[string]string{"error": "no route"})
+	})
+	engine.NoMethod(func(ctx *Context) {
+		_ = ctx.String(http.StatusMethodNotAllowed, "use "+ctx.W.Header().Get("Allow"))
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"error":"no route"}` {
+		t.Errorf("Expected custom 404, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/users/1", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "use GET, DELETE, HEAD, OPTIONS" {
+		t.Errorf("Expected custom 405, got %d %s", w.Code, w.Body.String())
+	}
+
+	// the engine middlewares still apply, so a panic is recovered
+	engine.NoRoute(func(ctx *Context) {
+		panic("boom")
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", w.Code)
+	}
+
+	// the handlers form a chain: Next runs the rest, Abort stops it
+	var trace []string
+	engine.NoRoute(func(ctx *Context) {
+		trace = append(trace, "first")
+		ctx.Next()
+		trace = append(trace, "first done")
+	}, func(ctx *Context) {
+		trace = append(trace, "second")
+		ctx.Abort()
+	}, func(ctx *Context) {
+		trace = append(trace, "third")
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/missing", nil))
+	if strings.Join(trace, ",") != "first,second,first done" {
+		t.Errorf("Expected first,second,first done, got %v", trace)
+	}
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 Not Found" {
+		t.Errorf("Expected the 404 fallback, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestEngineImplicitMethodsAndRedirects(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/users/:id", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "user "+ctx.Param("id"))
+	})
+	g.Post("/users/", func(ctx *Context) {})
+	g.Options("/explicit", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "explicit")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/api/users/1", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("Expected 200 with no body, got %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/api/users/1", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected 204 with Allow: GET, HEAD, OPTIONS, got %d %s", w.Code, w.Header().Get("Allow"))
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/api/explicit", nil))
+	if w.Body.String() != "explicit" {
+		t.Errorf("Expected the explicit OPTIONS handler, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without redirects, got %d", w.Code)
+	}
+	engine.RedirectTrailingSlash = true
+	engine.RedirectFixedPath = true
+	tests := []struct {
+		method, path string
+		code         int
+		location     string
+	}{
+		{http.MethodGet, "/api/users/1/?x=1", http.StatusMovedPermanently, "/api/users/1?x=1"},
+		{http.MethodPost, "/api/users", http.StatusPermanentRedirect, "/api/users/"},
+		{http.MethodGet, "/API/Users/Bob", http.StatusMovedPermanently, "/api/users/Bob"},
+		{http.MethodGet, "/api//users/../users/2", http.StatusMovedPermanently, "/api/users/2"},
+		{http.MethodPost, "/Api/Users", http.StatusPermanentRedirect, "/api/users/"},
+	}
+	for _, tt := range tests {
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		// Result holds the headers as sent, unlike the live Header map
+		if res := w.Result(); res.StatusCode != tt.code || res.Header.Get("Location") != tt.location {
+			t.Errorf("%s %s: expected %d to %s, got %d to %s", tt.method, tt.path, tt.code, tt.location, res.StatusCode, res.Header.Get("Location"))
+		}
+	}
+}
+
+func TestContextRedirect(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/old", func(ctx *Context) {
+		_ = ctx.Redirect(http.StatusFound, "/api/new")
+	})
+	g.Get("/bad", func(ctx *Context) {
+		if err := ctx.Redirect(http.StatusOK, "/api/new"); err != nil {
+			_ = ctx.String(http.StatusInternalServerError, err.Error())
+		}
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	res, err := client.Get(server.URL + "/api/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusFound || res.Header.Get("Location") != "/api/new" {
+		t.Errorf("Expected 302 to /api/new, got %d to %q", res.StatusCode, res.Header.Get("Location"))
+	}
+	res, err = client.Get(server.URL + "/api/bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for an invalid redirect code, got %d", res.StatusCode)
+	}
+}
+
+func TestContextNextAbort(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	var trace []string
+	g.UseHandlers(func(ctx *Context) {
+		trace = append(trace, "before")
+		ctx.Next()
+		trace = append(trace, "after")
+	})
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if ctx.GetHeader("X-Token") == "" {
+				return
+			}
+			next(ctx)
+		}
+	})
+	g.UseHandlers(func(ctx *Context) {
+		if ctx.GetHeader("X-Token") == "bad" {
+			_ = ctx.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+		}
+	})
+	g.Get("/secret", func(ctx *Context) {
+		trace = append(trace, "handler")
+		_ = ctx.String(http.StatusOK, "secret")
+	})
+
+	tests := []struct {
+		token string
+		code  int
+		trace string
+	}{
+		{"", http.StatusOK, "[before after]"},
+		{"bad", http.StatusForbidden, "[before after]"},
+		{"good", http.StatusOK, "[before handler after]"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		req := httptest.NewRequest(http.MethodGet, "/api/secret", nil)
+		req.Header.Set("X-Token", tt.token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.code || fmt.Sprint(trace) != tt.trace {
+			t.Errorf("token %q: expected %d %s, got %d %v", tt.token, tt.code, tt.trace, w.Code, trace)
+		}
+	}
+}
+
+func TestRecoveryStopsChain(t *testing.T) {
+	engine := newTestEngine()
+	ran := false
+	handler := func(ctx *Context) {
+		ran = true
+		_ = ctx.String(http.StatusOK, "secret")
+	}
+	use := engine.Group("use")
+	use.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			panic("auth failed")
+		}
+	})
+	use.Get("/secret", handler)
+	handlers := engine.Group("handlers")
+	handlers.UseHandlers(func(ctx *Context) {
+		panic("auth failed")
+	})
+	handlers.Get("/secret", handler)
+
+	for _, path := range []string{"/use/secret", "/handlers/secret"} {
+		ran = false
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if ran || w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected 500 without running the handler, got %d (handler ran: %v)", path, w.Code, ran)
+		}
+	}
+}
+
+func TestEngineAnyName(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Any("/items/:id", func(ctx *Context) {}).Name("item")
+	g.Get("/other", func(ctx *Context) {}).Name("item")
+
+	named := 0
+	for _, r := range engine.Routes() {
+		if r.Path == "/api/items/:id" {
+			if r.Name != "item" {
+				t.Errorf("Expected %s %s to be named item, got %q", r.Method, r.Path, r.Name)
+			}
+			named++
+		} else if r.Name != "" {
+			t.Errorf("Expected no name for %s %s, got %q", r.Method, r.Path, r.Name)
+		}
+	}
+	if named != 7 {
+		t.Errorf("Expected 7 routes for Any, got %d", named)
+	}
+	if u, err := engine.URL("item", map[string]string{"id": "3"}); err != nil || u != "/api/items/3" {
+		t.Errorf("Expected /api/items/3, got %s %v", u, err)
+	}
+}
+
+func showUser(ctx *Context) {}
+
+func TestEngineRoutesAndURL(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/users/{id:int}", showUser).Name("user.show")
+	g.Post("/users", func(ctx *Context) {}, Recovery)
+	g.Get("/files/**", func(ctx *Context) {}).Name("files")
+	g.Post("/users/{id:int}", func(ctx *Context) {}).Name("user.update")
+	g.Get("/files/**/bad", func(ctx *Context) {}).Name("bad")
+
+	routes := engine.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("Expected 3 routes, got %v", routes)
+	}
+	show := routes[0]
+	if show.Method != http.MethodGet || show.Path != "/api/users/{id:int}" || show.Name != "user.show" ||
+		!strings.HasSuffix(show.Handler, ".showUser") || show.Middlewares != 2 {
+		t.Errorf("Unexpected route info %+v", show)
+	}
+	if post := routes[2]; post.Method != http.MethodPost || post.Middlewares != 3 {
+		t.Errorf("Unexpected route info %+v", post)
+	}
+	// same path, different method: each route keeps its own name
+	if update := routes[3]; update.Method != http.MethodPost || update.Name != "user.update" {
+		t.Errorf("Unexpected route info %+v", update)
+	}
+	if _, err := engine.URL("bad", map[string]string{"id": "1"}); err == nil {
+		t.Error("Expected no name for a route that failed to register")
+	}
+
+	if u, err := engine.URL("user.show", map[string]string{"id": "42"}); err != nil || u != "/api/users/42" {
+		t.Errorf("Expected /api/users/42, got %s %v", u, err)
+	}
+	if u, err := engine.URL("files", map[string]string{"**": "a b/c.txt"}); err != nil || u != "/api/files/a%20b/c.txt" {
+		t.Errorf("Expected /api/files/a%%20b/c.txt, got %s %v", u, err)
+	}
+	if _, err := engine.URL("user.show", map[string]string{"id": "abc"}); err == nil {
+		t.Error("Expected an error for a param that fails its constraint")
+	}
+	if _, err := engine.URL("user.show", nil); err == nil {
+		t.Error("Expected an error for a missing param")
+	}
+	if _, err := engine.URL("missing", nil); err == nil {
+		t.Error("Expected an error for an unknown route name")
+	}
+}
+
+func TestContextBind(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	type updateUser struct {
+		ID   int    `uri:"id" json:"-"`
+		Name string `form:"name" json:"name"`
+	}
+	g.Put("/users/{id:int}", func(ctx *Context) {
+		var u updateUser
+		if err := ctx.BindURI(&u); err != nil {
+			return
+		}
+		if err := ctx.Bind(&u); err != nil {
+			return
+		}
+		_ = ctx.String(http.StatusOK, "%d %s", u.ID, u.Name)
+	})
+	for _, tt := range []struct {
+		contentType, body string
+	}{
+		{"application/json; charset=utf-8", `{"name":"bob"}`},
+		{"application/x-www-form-urlencoded", "name=bob"},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/users/7", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Body.String() != "7 bob" {
+			t.Errorf("%s: expected 7 bob, got %d %q", tt.contentType, w.Code, w.Body.String())
+		}
+	}
+
+	// per-request JSON options don't change the shared binding
+	g.Post("/strict", func(ctx *Context) {
+		ctx.DisallowUnknownFields = true
+		var u updateUser
+		if err := ctx.BindJson(&u); err != nil {
+			return
+		}
+		_ = ctx.String(http.StatusOK, u.Name)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/strict", strings.NewReader(`{"name":"bob","age":3}`))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown field, got %d", w.Code)
+	}
+	if binding.JSON.DisallowUnknownFields {
+		t.Error("Expected binding.JSON to be left unchanged")
+	}
+}
+
+func TestContextFormErrors(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Post("/form", func(ctx *Context) {
+		name, err := ctx.GetForm("name")
+		if err != nil {
+			ctx.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		_ = ctx.String(http.StatusOK, name)
+	})
+	g.Post("/upload", func(ctx *Context) {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := ctx.SaveFile(file, t.TempDir()+"/missing/"+file.Filename); err == nil {
+			ctx.Fail(http.StatusInternalServerError, "expected a save error")
+			return
+		}
+		_ = ctx.String(http.StatusOK, file.Filename)
+	})
+	tests := []struct {
+		name, path, contentType, body string
+		code                          int
+		want                          string
+	}{
+		{"urlencoded form", "/api/form", "application/x-www-form-urlencoded", "name=bob", http.StatusOK, "bob"},
+		{"multipart form", "/api/form", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nbob\r\n--b--\r\n", http.StatusOK, "bob"},
+		{"json body", "/api/form", "application/json", `{"name":"bob"}`, http.StatusOK, ""},
+		{"malformed urlencoded", "/api/form", "application/x-www-form-urlencoded", "name=%zz", http.StatusBadRequest, "invalid URL escape"},
+		{"missing boundary", "/api/form", "multipart/form-data", "name=bob", http.StatusBadRequest, "no multipart boundary"},
+		{"truncated multipart", "/api/form", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nbob", http.StatusBadRequest, "EOF"},
+		{"not multipart upload", "/api/upload", "application/x-www-form-urlencoded", "file=x", http.StatusBadRequest, http.ErrNotMultipart.Error()},
+		{"missing file", "/api/upload", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"other\"\r\n\r\nx\r\n--b--\r\n", http.StatusBadRequest, http.ErrMissingFile.Error()},
+		{"uploaded file", "/api/upload", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.txt\"\r\n\r\nx\r\n--b--\r\n", http.StatusOK, "a.txt"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.name, tt.code, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestContextBindMultipartTooLarge(t *testing.T) {
+	engine := newTestEngine()
+	type avatar struct {
+		File *multipart.FileHeader `form:"file" validate:"filesize=1KB"`
+	}
+	engine.Group("api").Post("/avatar", func(ctx *Context) {
+		var a avatar
+		_ = ctx.BindMultipart(&a)
+	})
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "a.png")
+	_, _ = fw.Write(bytes.Repeat([]byte("x"), 2<<20))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/avatar", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", w.Code)
+	}
+}
+
+func TestContextRenderFormats(t *testing.T) {
+	type payload struct {
+		Name string `yaml:"name" toml:"name" msgpack:"name"`
+	}
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/yaml", func(ctx *Context) { _ = ctx.YAML(http.StatusOK, payload{"a"}) })
+	g.Get("/toml", func(ctx *Context) { _ = ctx.TOML(http.StatusOK, payload{"a"}) })
+	g.Get("/msgpack", func(ctx *Context) { _ = ctx.MsgPack(http.StatusOK, payload{"a"}) })
+	g.Get("/protobuf", func(ctx *Context) { _ = ctx.ProtoBuf(http.StatusOK, wrapperspb.String("a")) })
+	g.Post("/echo", func(ctx *Context) {
+		var p payload
+		if err := ctx.Bind(&p); err != nil {
+			return
+		}
+		_ = ctx.String(http.StatusOK, p.Name)
+	})
+	tests := []struct {
+		path, contentType string
+		decode            func([]byte) (string, error)
+	}{
+		{"/api/yaml", "application/yaml; charset=utf-8", func(b []byte) (string, error) {
+			var p payload
+			err := yaml.Unmarshal(b, &p)
+			return p.Name, err
+		}},
+		{"/api/toml", "application/toml; charset=utf-8", func(b []byte) (string, error) {
+			var p payload
+			_, err := toml.Decode(string(b), &p)
+			return p.Name, err
+		}},
+		{"/api/msgpack", "application/msgpack", func(b []byte) (string, error) {
+			var p payload
+			err := msgpack.Unmarshal(b, &p)
+			return p.Name, err
+		}},
+		{"/api/protobuf", "application/x-protobuf", func(b []byte) (string, error) {
+			var msg wrapperspb.StringValue
+			err := proto.Unmarshal(b, &msg)
+			return msg.GetValue(), err
+		}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := w.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: expected Content-Type %s, got %s", tt.path, tt.contentType, got)
+		}
+		name, err := tt.decode(w.Body.Bytes())
+		if err != nil || name != "a" {
+			t.Errorf("%s: expected a, got %q %v", tt.path, name, err)
+		}
+		if tt.path == "/api/protobuf" {
+			continue
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/echo", bytes.NewReader(w.Body.Bytes()))
+		req.Header.Set("Content-Type", tt.contentType)
+		echo := httptest.NewRecorder()
+		engine.ServeHTTP(echo, req)
+		if echo.Body.String() != "a" {
+			t.Errorf("%s: expected the body to bind back, got %d %q", tt.path, echo.Code, echo.Body.String())
+		}
+	}
+}
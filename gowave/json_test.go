@@ -0,0 +1,118 @@
+package gowave
+
+import (
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChenGuo505/gowave/codec"
+)
+
+func TestContextJSONRenderers(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/indented", func(ctx *Context) { _ = ctx.IndentedJSON(http.StatusOK, map[string]int{"a": 1}) })
+	g.Get("/secure", func(ctx *Context) { _ = ctx.SecureJSON(http.StatusOK, []int{1, 2}) })
+	g.Get("/secure-object", func(ctx *Context) { _ = ctx.SecureJSON(http.StatusOK, map[string]int{"a": 1}) })
+	g.Get("/jsonp", func(ctx *Context) { _ = ctx.JSONP(http.StatusOK, map[string]int{"a": 1}) })
+	g.Get("/ascii", func(ctx *Context) { _ = ctx.AsciiJSON(http.StatusOK, map[string]string{"s": "é😀<"}) })
+	g.Get("/pure", func(ctx *Context) { _ = ctx.PureJSON(http.StatusOK, map[string]string{"s": "<b>&"}) })
+	g.Get("/stream", func(ctx *Context) { _ = ctx.StreamJSON(http.StatusOK, []int{1, 2, 3}) })
+	g.Get("/stream-seq", func(ctx *Context) {
+		var seq iter.Seq[any] = func(yield func(any) bool) {
+			for i := range 250 {
+				if !yield(map[string]int{"i": i}) {
+					return
+				}
+			}
+		}
+		_ = ctx.StreamJSON(http.StatusOK, seq)
+	})
+	tests := []struct {
+		path, contentType, body string
+		code                    int
+	}{
+		{"/api/indented", "application/json; charset=utf-8", "{\n    \"a\": 1\n}", http.StatusOK},
+		{"/api/secure", "application/json; charset=utf-8", "while(1);[1,2]", http.StatusOK},
+		{"/api/secure-object", "application/json; charset=utf-8", `{"a":1}`, http.StatusOK},
+		{"/api/jsonp", "application/json; charset=utf-8", `{"a":1}`, http.StatusOK},
+		{"/api/jsonp?callback=app.done", "application/javascript; charset=utf-8", `/**/app.done({"a":1});`, http.StatusOK},
+		{"/api/jsonp?callback=alert(1)//", "", "", http.StatusBadRequest},
+		{"/api/ascii", "application/json", `{"s":"\u00e9\ud83d\ude00\u003c"}`, http.StatusOK},
+		{"/api/pure", "application/json; charset=utf-8", "{\"s\":\"<b>&\"}\n", http.StatusOK},
+		{"/api/stream", "application/json; charset=utf-8", "[1\n,2\n,3\n]", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: expected Content-Type %s, got %s", tt.path, tt.contentType, got)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.body, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stream-seq", nil))
+	var items []map[string]int
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil || len(items) != 250 || items[249]["i"] != 249 {
+		t.Errorf("expected 250 streamed items, got %d %v", len(items), err)
+	}
+	if !w.Flushed {
+		t.Error("expected the stream to be flushed")
+	}
+}
+
+func TestContextRenderErrors(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	respond := func(err error, ctx *Context) {
+		if err != nil {
+			_ = ctx.String(http.StatusInternalServerError, "render failed")
+		}
+	}
+	g.Get("/json", func(ctx *Context) { respond(ctx.JSON(http.StatusOK, make(chan int)), ctx) })
+	g.Get("/secure", func(ctx *Context) { respond(ctx.SecureJSON(http.StatusOK, []any{make(chan int)}), ctx) })
+	g.Get("/stream", func(ctx *Context) { respond(ctx.StreamJSON(http.StatusOK, 42), ctx) })
+	for _, path := range []string{"/api/json", "/api/secure", "/api/stream"} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusInternalServerError || w.Body.String() != "render failed" {
+			t.Errorf("%s: expected the handler's 500, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+type countingCodec struct {
+	codec.StdJSON
+	marshals int
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshals++
+	return c.StdJSON.Marshal(v)
+}
+
+func TestJSONCodecIsPluggable(t *testing.T) {
+	c := &countingCodec{}
+	old := codec.JSON
+	codec.JSON = c
+	defer func() { codec.JSON = old }()
+
+	engine := newTestEngine()
+	engine.Group("api").Get("/json", func(ctx *Context) { _ = ctx.JSON(http.StatusOK, []int{1}) })
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/json", nil))
+	if w.Body.String() != "[1]" || c.marshals != 1 {
+		t.Errorf("expected the custom codec to render [1], got %q after %d calls", w.Body.String(), c.marshals)
+	}
+}
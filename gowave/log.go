@@ -41,6 +41,7 @@ type LogFormatterParams struct {
 	Request    *http.Request
 	Timestamp  time.Time
 	StatusCode int
+	BodySize   int
 	Latency    time.Duration
 	ClientIP   net.IP
 	Method     string
@@ -106,7 +107,7 @@ func LoggingWithConfig(conf LoggingConfig, next HandlerFunc) HandlerFunc {
 		latency := stop.Sub(start)
 		ip, _, _ := net.SplitHostPort(strings.TrimSpace(ctx.Req.RemoteAddr))
 		clientIP := net.ParseIP(ip)
-		statusCode := ctx.StatusCode
+		statusCode := ctx.Writer().Status()
 		if raw != "" {
 			path = path + "?" + raw
 		}
@@ -114,6 +115,7 @@ func LoggingWithConfig(conf LoggingConfig, next HandlerFunc) HandlerFunc {
 			Request:    ctx.Req,
 			Timestamp:  stop,
 			StatusCode: statusCode,
+			BodySize:   ctx.Writer().Size(),
 			Latency:    latency,
 			ClientIP:   clientIP,
 			Method:     method,
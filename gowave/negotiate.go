@@ -0,0 +1,160 @@
+package gowave
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ChenGuo505/gowave/binding"
+	"github.com/ChenGuo505/gowave/render"
+)
+
+const (
+	MIMEHTML  = "text/html"
+	MIMEPlain = "text/plain"
+)
+
+// ErrNotAcceptable is returned by Negotiate when the client accepts none
+// of the offered formats.
+var ErrNotAcceptable = errors.New("gowave: no offered format is acceptable")
+
+// Offers holds the data Negotiate can respond with, one field per format.
+// Formats left nil are not offered; when the client ranks several formats
+// equally, the earlier field wins.
+type Offers struct {
+	JSON     any
+	XML      any
+	YAML     any
+	TOML     any
+	MsgPack  any
+	ProtoBuf any
+	// HTML is executed with the HTMLName template of the engine, or written
+	// as is, and must then be a string, if HTMLName is empty.
+	HTML     any
+	HTMLName string
+	Text     any
+}
+
+type offer struct {
+	mimeTypes []string
+	data      any
+	render    func(data any) render.Render
+}
+
+func (c *Context) offers(o Offers) []offer {
+	all := []offer{
+		{[]string{binding.MIMEJSON}, o.JSON, func(d any) render.Render { return &render.JSON{Data: d} }},
+		{[]string{binding.MIMEXML, binding.MIMEXML2}, o.XML, func(d any) render.Render { return &render.XML{Data: d} }},
+		{[]string{binding.MIMEYAML2, binding.MIMEYAML}, o.YAML, func(d any) render.Render { return &render.YAML{Data: d} }},
+		{[]string{binding.MIMETOML}, o.TOML, func(d any) render.Render { return &render.TOML{Data: d} }},
+		{[]string{binding.MIMEMSGPACK2, binding.MIMEMSGPACK}, o.MsgPack, func(d any) render.Render { return &render.MsgPack{Data: d} }},
+		{[]string{binding.MIMEPROTOBUF}, o.ProtoBuf, func(d any) render.Render { return &render.ProtoBuf{Data: d} }},
+		{[]string{MIMEHTML}, o.HTML, func(d any) render.Render {
+			if o.HTMLName == "" {
+				return &render.HTML{Data: d}
+			}
+			return &render.HTML{Data: d, Name: o.HTMLName, IsTemplate: true, Template: c.engine.HTMLRender.Template}
+		}},
+		{[]string{MIMEPlain}, o.Text, func(d any) render.Render { return &render.String{Format: "%v", Data: []any{d}} }},
+	}
+	offered := all[:0]
+	for _, of := range all {
+		if of.data != nil {
+			offered = append(offered, of)
+		}
+	}
+	return offered
+}
+
+// Negotiate renders the offer that best matches the Accept header. If none
+// matches it responds 406 Not Acceptable and returns ErrNotAcceptable.
+func (c *Context) Negotiate(code int, o Offers) error {
+	offers := c.offers(o)
+	var mimeTypes []string
+	for _, of := range offers {
+		mimeTypes = append(mimeTypes, of.mimeTypes...)
+	}
+	c.W.Header().Add("Vary", "Accept")
+	format := c.NegotiateFormat(mimeTypes...)
+	for _, of := range offers {
+		for _, mimeType := range of.mimeTypes {
+			if mimeType == format {
+				return c.render(code, of.render(of.data))
+			}
+		}
+	}
+	c.AbortWithStatus(http.StatusNotAcceptable)
+	return ErrNotAcceptable
+}
+
+// NegotiateFormat returns the offered media type the client prefers
+// according to the q-values of its Accept header, or "" if it accepts
+// none. Offers the client ranks equally are picked in the order given; a
+// request without Accept gets the first offer.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return offered[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, of := range offered {
+		if q := acceptQuality(ranges, of); q > bestQ {
+			best, bestQ = of, q
+		}
+	}
+	return best
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+		if !ok {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptQuality returns the q-value of the most specific range matching
+// mimeType, so "text/html;q=0" excludes HTML even if "*/*" is accepted.
+func acceptQuality(ranges []mediaRange, mimeType string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mimeType), "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
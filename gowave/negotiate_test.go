@@ -0,0 +1,68 @@
+package gowave
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{"application/json", "application/xml", "text/html"}
+	tests := []struct {
+		accept, want string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"application/json;q=0.5, application/xml;q=0.8", "application/xml"},
+		{"text/*", "text/html"},
+		{"*/*;q=0.5, text/html;q=0", "application/json"},
+		{"application/*;q=0.2, application/xml", "application/xml"},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		c := &Context{Req: req}
+		if got := c.NegotiateFormat(offered...); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.accept, tt.want, got)
+		}
+	}
+}
+
+func TestContextNegotiate(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Get("/user", func(ctx *Context) {
+		user := map[string]string{"name": "bob"}
+		_ = ctx.Negotiate(http.StatusOK, Offers{JSON: user, HTML: "<b>bob</b>", Text: "bob"})
+	})
+	tests := []struct {
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"", http.StatusOK, "application/json; charset=utf-8", `{"name":"bob"}`},
+		{"text/html,*/*;q=0.8", http.StatusOK, "text/html; charset=utf-8", "<b>bob</b>"},
+		{"text/plain", http.StatusOK, "text/plain; charset=utf-8", "bob"},
+		{"application/xml", http.StatusNotAcceptable, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%q: expected %d %q %q, got %d %q %q", tt.accept, tt.code, tt.contentType, tt.body,
+				w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%q: expected Vary: Accept", tt.accept)
+		}
+	}
+}
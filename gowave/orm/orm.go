@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -19,6 +20,7 @@ type GWDB struct {
 
 type Session struct {
 	db        *GWDB
+	ctx       context.Context
 	tableName string
 
 	sqlStr string
@@ -78,6 +80,7 @@ func (g *GWDB) Close() error {
 func (g *GWDB) NewSession() *Session {
 	return &Session{
 		db:        g,
+		ctx:       context.Background(),
 		sqlStr:    "",
 		args:      make([]any, 0),
 		tx:        nil,
@@ -91,11 +94,18 @@ func (s *Session) Table(name string) *Session {
 	return s
 }
 
+// WithContext binds ctx to the session so that statements and transactions
+// are cancelled along with it, e.g. when passed a *gowave.Context.
+func (s *Session) WithContext(ctx context.Context) *Session {
+	s.ctx = ctx
+	return s
+}
+
 func (s *Session) Begin() error {
 	if s.isTxBegin {
 		return errors.New("transaction already started")
 	}
-	tx, err := s.db.db.Begin()
+	tx, err := s.db.db.BeginTx(s.ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -153,14 +163,14 @@ func (s *Session) Exec() (int64, int64, error) {
 		if s.tx == nil {
 			return -1, -1, errors.New("transaction not started")
 		}
-		stmt, err = s.tx.Prepare(s.sqlStr)
+		stmt, err = s.tx.PrepareContext(s.ctx, s.sqlStr)
 	} else {
-		stmt, err = s.db.db.Prepare(s.sqlStr)
+		stmt, err = s.db.db.PrepareContext(s.ctx, s.sqlStr)
 	}
 	if err != nil {
 		return -1, -1, err
 	}
-	res, err := stmt.Exec(s.args...)
+	res, err := stmt.ExecContext(s.ctx, s.args...)
 	if err != nil {
 		return -1, -1, err
 	}
@@ -195,14 +205,14 @@ func (s *Session) Query(item any) ([]any, error) {
 		if s.tx == nil {
 			return nil, errors.New("transaction not started")
 		}
-		stmt, err = s.tx.Prepare(s.sqlStr)
+		stmt, err = s.tx.PrepareContext(s.ctx, s.sqlStr)
 	} else {
-		stmt, err = s.db.db.Prepare(s.sqlStr)
+		stmt, err = s.db.db.PrepareContext(s.ctx, s.sqlStr)
 	}
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(s.args...)
+	rows, err := stmt.QueryContext(s.ctx, s.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -273,15 +283,15 @@ func (s *Session) QueryRow() (int64, error) {
 		if s.tx == nil {
 			return -1, errors.New("transaction not started")
 		}
-		stmt, err = s.tx.Prepare(s.sqlStr)
+		stmt, err = s.tx.PrepareContext(s.ctx, s.sqlStr)
 	} else {
-		stmt, err = s.db.db.Prepare(s.sqlStr)
+		stmt, err = s.db.db.PrepareContext(s.ctx, s.sqlStr)
 	}
 	if err != nil {
 		return -1, err
 	}
 	var count int64
-	err = stmt.QueryRow(s.args...).Scan(&count)
+	err = stmt.QueryRowContext(s.ctx, s.args...).Scan(&count)
 	if err != nil {
 		return -1, err
 	}
@@ -25,6 +25,7 @@ func Recovery(next HandlerFunc) HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				ctx.Logger.Error(errorDetails(err))
+				ctx.Abort()
 				ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
@@ -1,10 +1,32 @@
 package render
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"fmt"
+	"iter"
 	"net/http"
+	"reflect"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/ChenGuo505/gowave/codec"
 )
 
+// DefaultSecureJSONPrefix is prepended to SecureJSON array responses so that
+// they cannot be executed by a <script> tag.
+const DefaultSecureJSONPrefix = "while(1);"
+
+// streamFlushEvery is how many StreamJSON elements are written between
+// flushes.
+const streamFlushEvery = 100
+
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+// callbackPattern accepts dotted JavaScript identifiers such as
+// "cb" or "jQuery.handlers.done".
+var callbackPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
 type JSON struct {
 	Data any
 }
@@ -14,10 +36,213 @@ func (j *JSON) SetContentType(w http.ResponseWriter) {
 }
 
 func (j *JSON) Render(w http.ResponseWriter) error {
-	jsonData, err := json.Marshal(j.Data)
+	jsonData, err := codec.JSON.Marshal(j.Data)
 	if err != nil {
 		return err
 	}
 	_, err = w.Write(jsonData)
 	return err
 }
+
+type IndentedJSON struct {
+	Data any
+}
+
+func (j *IndentedJSON) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/json; charset=utf-8")
+}
+
+func (j *IndentedJSON) Render(w http.ResponseWriter) error {
+	jsonData, err := codec.JSON.MarshalIndent(j.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+
+// SecureJSON prefixes array responses with Prefix, DefaultSecureJSONPrefix
+// if empty, to prevent JSON hijacking.
+type SecureJSON struct {
+	Prefix string
+	Data   any
+}
+
+func (j *SecureJSON) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/json; charset=utf-8")
+}
+
+func (j *SecureJSON) Render(w http.ResponseWriter) error {
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(jsonData, []byte("[")) && bytes.HasSuffix(jsonData, []byte("]")) {
+		prefix := j.Prefix
+		if prefix == "" {
+			prefix = DefaultSecureJSONPrefix
+		}
+		if _, err = w.Write([]byte(prefix)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+
+// JSONP wraps the JSON in a call to Callback. Without a callback it renders
+// plain JSON.
+type JSONP struct {
+	Callback string
+	Data     any
+}
+
+// ValidCallback reports whether name is safe to use as a JSONP callback.
+func ValidCallback(name string) bool {
+	return callbackPattern.MatchString(name)
+}
+
+func (j *JSONP) SetContentType(w http.ResponseWriter) {
+	if j.Callback == "" {
+		setContentType(w, "application/json; charset=utf-8")
+		return
+	}
+	setContentType(w, "application/javascript; charset=utf-8")
+}
+
+func (j *JSONP) Render(w http.ResponseWriter) error {
+	if j.Callback != "" && !ValidCallback(j.Callback) {
+		return ErrInvalidCallback
+	}
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	if j.Callback == "" {
+		_, err = w.Write(jsonData)
+		return err
+	}
+	// the leading comment keeps the response from starting with
+	// attacker-controlled bytes
+	var buf bytes.Buffer
+	buf.Grow(len(j.Callback) + len(jsonData) + 8)
+	buf.WriteString("/**/")
+	buf.WriteString(j.Callback)
+	buf.WriteByte('(')
+	buf.Write(jsonData)
+	buf.WriteString(");")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// AsciiJSON escapes every non-ASCII character as \uXXXX.
+type AsciiJSON struct {
+	Data any
+}
+
+func (j *AsciiJSON) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/json")
+}
+
+func (j *AsciiJSON) Render(w http.ResponseWriter) error {
+	jsonData, err := codec.JSON.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(jsonData))
+	for len(jsonData) > 0 {
+		r, size := utf8.DecodeRune(jsonData)
+		switch {
+		case r < utf8.RuneSelf:
+			buf.WriteByte(jsonData[0])
+		case r > 0xffff:
+			// characters outside the BMP become a UTF-16 surrogate pair
+			r -= 0x10000
+			fmt.Fprintf(&buf, `\u%04x\u%04x`, 0xd800+(r>>10), 0xdc00+(r&0x3ff))
+		default:
+			fmt.Fprintf(&buf, `\u%04x`, r)
+		}
+		jsonData = jsonData[size:]
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// PureJSON leaves <, > and & unescaped.
+type PureJSON struct {
+	Data any
+}
+
+func (j *PureJSON) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/json; charset=utf-8")
+}
+
+func (j *PureJSON) Render(w http.ResponseWriter) error {
+	encoder := codec.JSON.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(j.Data)
+}
+
+// StreamJSON writes Data, a slice, an array or an iter.Seq[any], as a JSON
+// array one element at a time, flushing as it goes, so large results are
+// never marshalled into a single buffer.
+type StreamJSON struct {
+	Data any
+}
+
+func (j *StreamJSON) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/json; charset=utf-8")
+}
+
+// Validate reports whether Data can be streamed, before anything is written.
+func (j *StreamJSON) Validate() error {
+	_, err := elements(j.Data)
+	return err
+}
+
+func (j *StreamJSON) Render(w http.ResponseWriter) error {
+	seq, err := elements(j.Data)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write([]byte("[")); err != nil {
+		return err
+	}
+	rc := http.NewResponseController(w)
+	encoder := codec.JSON.NewEncoder(w)
+	n := 0
+	for v := range seq {
+		if n > 0 {
+			if _, err = w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		if err = encoder.Encode(v); err != nil {
+			return err
+		}
+		n++
+		if n%streamFlushEvery == 0 {
+			_ = rc.Flush()
+		}
+	}
+	_, err = w.Write([]byte("]"))
+	return err
+}
+
+func elements(data any) (iter.Seq[any], error) {
+	if seq, ok := data.(iter.Seq[any]); ok {
+		return seq, nil
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("render: StreamJSON needs a slice, an array or an iter.Seq[any], got %T", data)
+	}
+	return func(yield func(any) bool) {
+		for i := 0; i < v.Len(); i++ {
+			if !yield(v.Index(i).Interface()) {
+				return
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,24 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type MsgPack struct {
+	Data any
+}
+
+func (m *MsgPack) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/msgpack")
+}
+
+func (m *MsgPack) Render(w http.ResponseWriter) error {
+	data, err := msgpack.Marshal(m.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
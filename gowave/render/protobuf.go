@@ -0,0 +1,29 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type ProtoBuf struct {
+	Data any
+}
+
+func (p *ProtoBuf) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/x-protobuf")
+}
+
+func (p *ProtoBuf) Render(w http.ResponseWriter) error {
+	msg, ok := p.Data.(proto.Message)
+	if !ok {
+		return errors.New("render: data must be a proto.Message")
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
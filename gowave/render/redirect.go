@@ -18,7 +18,7 @@ func (r *Redirect) SetContentType(w http.ResponseWriter) {
 }
 
 func (r *Redirect) Render(w http.ResponseWriter) error {
-	if (r.Code < http.StatusMultipleChoices || r.Code >= http.StatusPermanentRedirect) && r.Code != http.StatusCreated {
+	if (r.Code < http.StatusMultipleChoices || r.Code > http.StatusPermanentRedirect) && r.Code != http.StatusCreated {
 		return fmt.Errorf("invalid redirect status code: %d", r.Code)
 	}
 	http.Redirect(w, r.Req, r.URL, r.Code)
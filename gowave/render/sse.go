@@ -0,0 +1,67 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ChenGuo505/gowave/codec"
+)
+
+// SSEvent is a single Server-Sent Event. String data is sent as is, one
+// data line per line of text; other data is sent as JSON.
+type SSEvent struct {
+	ID    string
+	Event string
+	Retry uint // reconnection delay in milliseconds, 0 leaves it unset
+	Data  any
+}
+
+func (s *SSEvent) SetContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+	}
+}
+
+func (s *SSEvent) Render(w http.ResponseWriter) error {
+	var sb strings.Builder
+	if s.ID != "" {
+		sb.WriteString("id: " + stripNewlines(s.ID) + "\n")
+	}
+	if s.Event != "" {
+		sb.WriteString("event: " + stripNewlines(s.Event) + "\n")
+	}
+	if s.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", s.Retry))
+	}
+	var data string
+	switch d := s.Data.(type) {
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := codec.JSON.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	// a lone \r also ends a line for the client, so it must not reach it
+	// inside a data line where it could start an event:, id: or retry: field
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	_, err := w.Write([]byte(sb.String()))
+	return err
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
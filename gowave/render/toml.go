@@ -0,0 +1,25 @@
+package render
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/BurntSushi/toml"
+)
+
+type TOML struct {
+	Data any
+}
+
+func (t *TOML) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/toml; charset=utf-8")
+}
+
+func (t *TOML) Render(w http.ResponseWriter) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(t.Data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,24 @@
+package render
+
+import (
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+type YAML struct {
+	Data any
+}
+
+func (y *YAML) SetContentType(w http.ResponseWriter) {
+	setContentType(w, "application/yaml; charset=utf-8")
+}
+
+func (y *YAML) Render(w http.ResponseWriter) error {
+	data, err := yaml.Marshal(y.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,124 @@
+package gowave
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter is the writer behind Context.W. It records the status and
+// size of the response and passes Flush, Hijack and Push through to the
+// connection's writer.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	// Status returns the status code sent, or 200 if none was sent yet.
+	Status() int
+	// Size returns the number of body bytes written.
+	Size() int
+	// Written reports whether the status line and headers were sent.
+	Written() bool
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	written bool
+	// mirror is kept equal to status for the deprecated Context.StatusCode
+	mirror *int
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(rw http.ResponseWriter) {
+	w.ResponseWriter = rw
+	w.setStatus(http.StatusOK)
+	w.size = 0
+	w.written = false
+}
+
+func (w *responseWriter) setStatus(code int) {
+	w.status = code
+	if w.mirror != nil {
+		*w.mirror = code
+	}
+}
+
+// WriteHeader sends the status once; later calls are ignored instead of
+// reaching net/http as superfluous. Informational 1xx statuses other than
+// 101 may be sent any number of times before it.
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.setStatus(code)
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is used by http.ResponseController to report flush errors.
+func (w *responseWriter) FlushError() error {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	for rw := w.ResponseWriter; rw != nil; {
+		if pusher, ok := rw.(http.Pusher); ok {
+			return pusher.Push(target, opts)
+		}
+		u, ok := rw.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		rw = u.Unwrap()
+	}
+	return http.ErrNotSupported
+}
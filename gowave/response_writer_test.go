@@ -0,0 +1,104 @@
+package gowave
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResponseWriterTracksStatus(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestEngine()
+	var logged *LogFormatterParams
+	g := engine.Group("api")
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return LoggingWithConfig(LoggingConfig{Formatter: func(params *LogFormatterParams) string {
+			logged = params
+			return ""
+		}}, next)
+	})
+	g.Get("/direct", func(ctx *Context) {
+		ctx.W.WriteHeader(http.StatusAccepted)
+		ctx.W.WriteHeader(http.StatusTeapot)
+		_, _ = ctx.W.Write([]byte("ok"))
+	})
+	g.Get("/file", func(ctx *Context) {
+		ctx.File(filepath.Join(dir, "a.txt"))
+	})
+	g.Get("/missing", func(ctx *Context) {
+		ctx.File(filepath.Join(dir, "missing.txt"))
+	})
+	g.Get("/empty", func(ctx *Context) {})
+	tests := []struct {
+		path         string
+		status, size int
+	}{
+		{"/api/direct", http.StatusAccepted, 2},
+		{"/api/file", http.StatusOK, 5},
+		{"/api/missing", http.StatusNotFound, len("404 page not found\n")},
+		{"/api/empty", http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || logged == nil || logged.StatusCode != tt.status || logged.BodySize != tt.size {
+			t.Errorf("%s: expected %d with %d bytes, got %d and logged %+v", tt.path, tt.status, tt.size, w.Code, logged)
+		}
+		logged = nil
+	}
+}
+
+func TestResponseWriterPassThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var w responseWriter
+	w.reset(&wrappedWriter{rec})
+	if w.Written() || w.Status() != http.StatusOK {
+		t.Errorf("Expected a fresh writer, got written=%v status=%d", w.Written(), w.Status())
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+	if w.Written() {
+		t.Error("Expected 1xx statuses not to count as written")
+	}
+	w.Flush()
+	if !rec.Flushed || !w.Written() || w.Status() != http.StatusOK {
+		t.Errorf("Expected Flush to reach the recorder and send 200, got flushed=%v status=%d", rec.Flushed, w.Status())
+	}
+	if _, _, err := w.Hijack(); err == nil {
+		t.Error("Expected Hijack to fail on a recorder")
+	}
+	if err := w.Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	if w.Unwrap() == nil {
+		t.Error("Expected Unwrap to return the wrapped writer")
+	}
+}
+
+func TestContextStatusCodeMirrorsWriter(t *testing.T) {
+	engine := newTestEngine()
+	var seen []int
+	g := engine.Group("api")
+	g.UseHandlers(func(ctx *Context) {
+		seen = append(seen, ctx.StatusCode)
+		ctx.Next()
+		seen = append(seen, ctx.StatusCode, ctx.Writer().Status())
+	})
+	g.Get("/created", func(ctx *Context) {
+		_ = ctx.String(http.StatusCreated, "ok")
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/created", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/created", nil))
+	want := []int{http.StatusOK, http.StatusCreated, http.StatusCreated}
+	for i, code := range seen {
+		if code != want[i%3] {
+			t.Errorf("Expected %v twice, got %v", want, seen)
+			break
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package gowave
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Route is returned by route registration so the route can be named.
+type Route struct {
+	Method     string
+	Path       string
+	router     *router
+	registered bool     // false if registration failed
+	also       []*Route // the other methods registered along with it by Any
+}
+
+// routeKey identifies a route by method and path pattern.
+type routeKey struct {
+	method string
+	path   string
+}
+
+// Name registers name for the route so that Engine.URL can build concrete
+// paths for it. A route returned by Any is named for every method. A name
+// may cover several methods of one path, but not different paths.
+func (r *Route) Name(name string) *Route {
+	r.name(name)
+	for _, other := range r.also {
+		other.name(name)
+	}
+	return r
+}
+
+func (r *Route) name(name string) {
+	if !r.registered {
+		r.router.engine.Logger.Error(fmt.Sprintf("route name %s not set: %s %s is not registered", name, r.Method, r.Path))
+		return
+	}
+	key := routeKey{method: r.Method, path: r.Path}
+	keys := r.router.names[name]
+	if len(keys) > 0 && keys[0].path != key.path {
+		r.router.engine.Logger.Error(fmt.Sprintf("duplicate route name %s for %s %s and %s %s", name, keys[0].method, keys[0].path, r.Method, r.Path))
+		return
+	}
+	if !slices.Contains(keys, key) {
+		r.router.names[name] = append(keys, key)
+	}
+}
+
+type RouteInfo struct {
+	Method      string
+	Path        string
+	Name        string
+	Handler     string // function name of the route handler
+	Middlewares int    // number of handlers that run before it
+	HandlerFunc HandlerFunc
+}
+
+// Routes lists every registered route, grouped by method in the order the
+// methods were first registered.
+func (e *Engine) Routes() []RouteInfo {
+	names := make(map[routeKey]string, len(e.names))
+	for name, keys := range e.names {
+		for _, key := range keys {
+			names[key] = name
+		}
+	}
+	var routes []RouteInfo
+	for _, tree := range e.trees {
+		tree.root.walk(func(n *treeNode) {
+			handler := n.route.handlers[len(n.route.handlers)-1]
+			routes = append(routes, RouteInfo{
+				Method:      tree.method,
+				Path:        n.pattern,
+				Name:        names[routeKey{method: tree.method, path: n.pattern}],
+				Handler:     nameOfFunction(handler),
+				Middlewares: len(n.route.handlers) - 1,
+				HandlerFunc: handler,
+			})
+		})
+	}
+	return routes
+}
+
+func (e *Engine) logRoutes() {
+	for _, r := range e.Routes() {
+		name := ""
+		if r.Name != "" {
+			name = " [" + r.Name + "]"
+		}
+		e.Logger.Debug(fmt.Sprintf("%-7s %-40s --> %s (%d middlewares)%s", r.Method, r.Path, r.Handler, r.Middlewares, name))
+	}
+}
+
+// URL builds the path of the route registered under name, filling its
+// params from params. "*" and "**" segments are filled from the "*" and
+// "**" keys.
+func (e *Engine) URL(name string, params map[string]string) (string, error) {
+	keys, ok := e.names[name]
+	if !ok {
+		return "", fmt.Errorf("route %s not found", name)
+	}
+	pattern := keys[0].path
+	strList := strings.Split(pattern, "/")
+	var sb strings.Builder
+	for idx, str := range strList {
+		if idx == 0 {
+			continue
+		}
+		sb.WriteString("/")
+		seg, err := parseSegment(str, pattern)
+		if err != nil {
+			return "", err
+		}
+		if seg.kind == kindStatic {
+			sb.WriteString(str)
+			continue
+		}
+		value, ok := params[seg.key]
+		if !ok || value == "" {
+			return "", fmt.Errorf("route %s: missing param %s", name, seg.key)
+		}
+		if seg.kind == kindCatchAll {
+			for i, part := range strings.Split(value, "/") {
+				if i > 0 {
+					sb.WriteString("/")
+				}
+				sb.WriteString(url.PathEscape(part))
+			}
+			continue
+		}
+		if seg.matcher != nil && !seg.matcher(value) {
+			return "", fmt.Errorf("route %s: param %s=%s does not satisfy %s", name, seg.key, value, seg.constraint)
+		}
+		sb.WriteString(url.PathEscape(value))
+	}
+	return sb.String(), nil
+}
+
+func (n *treeNode) walk(fn func(n *treeNode)) {
+	if n.route != nil {
+		fn(n)
+	}
+	for _, child := range n.statics {
+		child.walk(fn)
+	}
+	for _, child := range n.dynamics {
+		child.walk(fn)
+	}
+}
+
+func nameOfFunction(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
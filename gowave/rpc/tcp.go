@@ -433,7 +433,10 @@ func (c *TcpClient) Connect(service string) error {
 	return nil
 }
 
-func (c *TcpClient) Invoke(_ context.Context, service string, method string, args []any) (any, error) {
+// Invoke calls service.method and waits for the response. If ctx ends
+// first the connection is closed, since a late response would otherwise be
+// read by the next call, and ctx.Err() is returned.
+func (c *TcpClient) Invoke(ctx context.Context, service string, method string, args []any) (any, error) {
 	req := &Request{
 		RequestID:   time.Now().UnixNano(),
 		ServiceName: service,
@@ -455,13 +458,28 @@ func (c *TcpClient) Invoke(_ context.Context, service string, method string, arg
 	if err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.conn.SetWriteDeadline(deadline)
+		defer c.conn.conn.SetWriteDeadline(time.Time{})
+	}
 	err = c.conn.Send(header, body)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, err
 	}
 	go c.readHandler(c.conn.respChan)
-	resp := <-c.conn.respChan
-	return resp, nil
+	select {
+	case resp := <-c.conn.respChan:
+		return resp, nil
+	case <-ctx.Done():
+		_ = c.conn.conn.Close()
+		return nil, ctx.Err()
+	}
 }
 
 func (c *TcpClient) Close() error {
@@ -548,6 +566,10 @@ func (p *TcpClientProxy) Call(ctx context.Context, service string, method string
 	for i := 0; i < p.option.Retries; i++ {
 		res, err := client.Invoke(ctx, service, method, args)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				_ = client.Close()
+				return nil, ctxErr
+			}
 			if i >= p.option.Retries-1 {
 				log.GWLogger.Error(fmt.Sprintf("rpc call %s.%s failed after %d retries: %v", service, method, p.option.Retries, err))
 				err := client.Close()
@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTcpClientInvokeCanceled(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	// the server reads the request but never answers
+	go func() { _, _ = io.Copy(io.Discard, server) }()
+	c := NewTcpClient(DefaultTcpClientOption)
+	c.conn = &TcpConn{conn: client, respChan: make(chan *Response, 1)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Invoke(ctx, "user", "Get", []any{1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Invoke returned %v after the deadline", time.Since(start))
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Invoke(canceled, "user", "Get", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package gowave
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// HookFunc is a lifecycle hook registered with OnStart or OnShutdown.
+type HookFunc func(ctx context.Context) error
+
+// OnStart registers hooks that run, in order, before the server starts
+// accepting connections. An error from a hook aborts the start.
+func (e *Engine) OnStart(hooks ...HookFunc) {
+	e.onStart = append(e.onStart, hooks...)
+}
+
+// OnShutdown registers hooks that run, in order, once in-flight requests
+// have drained, e.g. to deregister the service or close database pools.
+func (e *Engine) OnShutdown(hooks ...HookFunc) {
+	e.onShutdown = append(e.onShutdown, hooks...)
+}
+
+// Shutdown stops the server from accepting connections, waits for in-flight
+// requests until ctx is done, then runs the OnShutdown hooks and closes the
+// register client. Only the first call on a running server has any effect;
+// later calls return its result. Called before Run, it does nothing.
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	srv := e.server
+	e.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	e.shutdownOnce.Do(func() {
+		defer close(e.done)
+		var errs []error
+		errs = append(errs, srv.Shutdown(ctx))
+		for _, hook := range e.onShutdown {
+			errs = append(errs, hook(ctx))
+		}
+		if e.register != nil {
+			errs = append(errs, e.register.Close())
+		}
+		e.shutdownErr = errors.Join(errs...)
+	})
+	return e.shutdownErr
+}
+
+// Run serves on addrs, which are host:port or unix:/path addresses. With
+// no addrs it uses HttpConfig.Listen, or HttpConfig.Host and Port (8080 by
+// default). TLS is used when HttpConfig.TLS names a cert and key file.
+func (e *Engine) Run(addrs ...string) {
+	tlsConf := e.HttpConfig.TLS
+	if tlsConf.CertFile != "" && tlsConf.KeyFile != "" {
+		e.runTLS(addrs, tlsConf.CertFile, tlsConf.KeyFile)
+		return
+	}
+	listeners, err := e.listen(addrs)
+	if err != nil {
+		e.Logger.Fatal(fmt.Sprintf("Failed to start server: %v", err))
+		return
+	}
+	e.runListeners(e.newServer(), listeners, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
+
+// RunH2C serves HTTP/2 without TLS (h2c) alongside HTTP/1.1, for use behind
+// load balancers that speak cleartext HTTP/2. addrs are as for Run.
+func (e *Engine) RunH2C(addrs ...string) {
+	listeners, err := e.listen(addrs)
+	if err != nil {
+		e.Logger.Fatal(fmt.Sprintf("Failed to start h2c server: %v", err))
+		return
+	}
+	srv := e.newServer()
+	srv.Handler = h2c.NewHandler(e, &http2.Server{IdleTimeout: e.HttpConfig.IdleTimeout})
+	e.runListeners(srv, listeners, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
+
+// RunWithTLS serves HTTPS on addr, or on the addresses Run would use if addr
+// is empty. Empty certFile and keyFile fall back to HttpConfig.TLS. With
+// HttpConfig.TLS.Reload set, the cert and key are reloaded from disk when
+// the files change, so rotated certificates need no restart.
+func (e *Engine) RunWithTLS(addr, certFile, keyFile string) {
+	var addrs []string
+	if addr != "" {
+		addrs = append(addrs, addr)
+	}
+	e.runTLS(addrs, certFile, keyFile)
+}
+
+func (e *Engine) runTLS(addrs []string, certFile, keyFile string) {
+	if certFile == "" && keyFile == "" {
+		certFile, keyFile = e.HttpConfig.TLS.CertFile, e.HttpConfig.TLS.KeyFile
+	}
+	srv := e.newServer()
+	if e.HttpConfig.TLS.Reload {
+		reloader, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			e.Logger.Fatal(fmt.Sprintf("Failed to load TLS certificate: %v", err))
+			return
+		}
+		srv.TLSConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+		certFile, keyFile = "", ""
+	}
+	listeners, err := e.listen(addrs)
+	if err != nil {
+		e.Logger.Fatal(fmt.Sprintf("Failed to start server with TLS: %v", err))
+		return
+	}
+	e.runListeners(srv, listeners, func(srv *http.Server, l net.Listener) error {
+		return srv.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// RunUnix serves on a Unix domain socket at file.
+func (e *Engine) RunUnix(file string) {
+	e.Run("unix:" + file)
+}
+
+// RunListener serves on a listener created by the caller.
+func (e *Engine) RunListener(l net.Listener) {
+	e.runListeners(e.newServer(), []net.Listener{l}, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+}
+
+func (e *Engine) runListeners(srv *http.Server, listeners []net.Listener, serveFn func(srv *http.Server, l net.Listener) error) {
+	e.logRoutes()
+	for _, l := range listeners {
+		e.Logger.Info(fmt.Sprintf("Starting server on %s:%s", l.Addr().Network(), l.Addr()))
+	}
+	if err := e.serve(srv, listeners, serveFn); err != nil {
+		e.Logger.Fatal(fmt.Sprintf("Failed to start server: %v", err))
+	}
+}
+
+func (e *Engine) listen(addrs []string) ([]net.Listener, error) {
+	if len(addrs) == 0 {
+		addrs = e.HttpConfig.Listen
+	}
+	if len(addrs) == 0 {
+		port := e.HttpConfig.Port
+		if port == 0 {
+			port = 8080
+		}
+		addrs = []string{net.JoinHostPort(e.HttpConfig.Host, strconv.Itoa(port))}
+	}
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		network := "tcp"
+		if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+			network, addr = "unix", path
+		}
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func (e *Engine) newServer() *http.Server {
+	conf := e.HttpConfig
+	return &http.Server{
+		Handler:           e,
+		ReadTimeout:       conf.ReadTimeout,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+		MaxHeaderBytes:    conf.MaxHeaderBytes,
+	}
+}
+
+// serve runs the OnStart hooks and then serves every listener until one
+// fails or the server is shut down, either by Shutdown or on SIGINT/SIGTERM,
+// in which case in-flight requests get ShutdownTimeout to finish. It
+// returns once the shutdown has completed.
+func (e *Engine) serve(srv *http.Server, listeners []net.Listener, serveFn func(srv *http.Server, l net.Listener) error) error {
+	e.mu.Lock()
+	e.server = srv
+	e.mu.Unlock()
+
+	ctx := context.Background()
+	for _, hook := range e.onStart {
+		if err := hook(ctx); err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			e.mu.Lock()
+			e.server = nil
+			e.mu.Unlock()
+			return err
+		}
+	}
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errCh <- serveFn(srv, l)
+		}(l)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			_ = srv.Close()
+			return err
+		}
+		<-e.done
+	case <-sigCtx.Done():
+		stop()
+		e.Logger.Info("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(ctx, e.ShutdownTimeout)
+		defer cancel()
+		if err := e.Shutdown(shutdownCtx); err != nil {
+			e.Logger.Error(fmt.Sprintf("Failed to shut down server: %v", err))
+		}
+	}
+	e.Logger.Info("Server stopped")
+	return nil
+}
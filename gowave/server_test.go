@@ -0,0 +1,230 @@
+package gowave
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func waitForServer(t *testing.T, addr string) {
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s did not start", addr)
+}
+
+func TestEngineGracefulShutdown(t *testing.T) {
+	engine := newTestEngine()
+	started := make(chan struct{})
+	g := engine.Group("")
+	g.Get("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_ = ctx.String(http.StatusOK, "done")
+	})
+	var hooks []string
+	engine.OnStart(func(ctx context.Context) error {
+		hooks = append(hooks, "start")
+		return nil
+	})
+	engine.OnShutdown(func(ctx context.Context) error {
+		hooks = append(hooks, "shutdown")
+		return nil
+	})
+
+	engine.HttpConfig.Port = freePort(t)
+	stopped := make(chan struct{})
+	go func() {
+		engine.Run()
+		close(stopped)
+	}()
+	addr := fmt.Sprintf("127.0.0.1:%d", engine.HttpConfig.Port)
+	waitForServer(t, addr)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		resCh <- result{body: string(body), err: err}
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if res := <-resCh; res.err != nil || res.body != "done" {
+		t.Errorf("Expected the in-flight request to finish, got %q %v", res.body, res.err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Shutdown")
+	}
+	if fmt.Sprint(hooks) != "[start shutdown]" {
+		t.Errorf("Expected [start shutdown], got %v", hooks)
+	}
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("Expected the listener to be closed")
+	}
+}
+
+func TestEngineListeners(t *testing.T) {
+	engine := newTestEngine()
+	engine.HttpConfig.ReadHeaderTimeout = time.Second
+	engine.HttpConfig.MaxHeaderBytes = 4 << 10
+	g := engine.Group("")
+	g.Get("/ping", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "pong")
+	})
+	tcpAddr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	sock := filepath.Join(t.TempDir(), "gowave.sock")
+	stopped := make(chan struct{})
+	go func() {
+		engine.Run(tcpAddr, "unix:"+sock)
+		close(stopped)
+	}()
+	waitForServer(t, tcpAddr)
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	for _, c := range []struct {
+		client *http.Client
+		url    string
+	}{
+		{http.DefaultClient, "http://" + tcpAddr + "/ping"},
+		{unixClient, "http://unix/ping"},
+	} {
+		resp, err := c.client.Get(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != "pong" {
+			t.Errorf("%s: expected pong, got %q", c.url, body)
+		}
+	}
+	engine.mu.Lock()
+	srv := engine.server
+	engine.mu.Unlock()
+	if srv.ReadHeaderTimeout != time.Second || srv.MaxHeaderBytes != 4<<10 {
+		t.Errorf("Expected HttpConfig to be applied, got %v %d", srv.ReadHeaderTimeout, srv.MaxHeaderBytes)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+}
+
+func TestEngineRunListener(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("")
+	g.Get("/ping", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "pong")
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		engine.RunListener(l)
+		close(stopped)
+	}()
+	resp, err := http.Get("http://" + l.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("Expected pong, got %q", body)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+}
+
+func TestEngineOnStartErrorClosesListeners(t *testing.T) {
+	engine := newTestEngine()
+	engine.OnStart(func(ctx context.Context) error {
+		return fmt.Errorf("boom")
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = engine.serve(engine.newServer(), []net.Listener{l}, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("Expected the hook error, got %v", err)
+	}
+	if conn, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		_ = conn.Close()
+		t.Error("Expected the listener to be closed")
+	}
+}
+
+func TestEngineShutdownBeforeRun(t *testing.T) {
+	engine := newTestEngine()
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan struct{})
+	go func() {
+		engine.RunListener(l)
+		close(stopped)
+	}()
+	// a served response means Run has set up the server
+	resp, err := http.Get("http://" + l.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after a Shutdown that followed an early one")
+	}
+}
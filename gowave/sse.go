@@ -0,0 +1,65 @@
+package gowave
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ChenGuo505/gowave/render"
+)
+
+// SSEvent sends a Server-Sent Event named name and flushes it to the
+// client. An empty name sends an unnamed "message" event.
+func (c *Context) SSEvent(name string, data any) error {
+	return c.sse(&render.SSEvent{Event: name, Data: data})
+}
+
+// SSEventWithID is SSEvent with an event id, which the client sends back
+// as Last-Event-ID when it reconnects.
+func (c *Context) SSEventWithID(id, name string, data any) error {
+	return c.sse(&render.SSEvent{ID: id, Event: name, Data: data})
+}
+
+// LastEventID returns the id of the last event a reconnecting client
+// received, so the stream can resume after it.
+func (c *Context) LastEventID() string {
+	return c.GetHeader("Last-Event-ID")
+}
+
+func (c *Context) sse(event *render.SSEvent) error {
+	event.SetContentType(c.W)
+	if err := event.Render(c.W); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+// Stream calls step until it returns false or the client disconnects,
+// flushing after each call. It reports whether the client went away.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.W)
+			if err := c.flush(); err != nil {
+				return true
+			}
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// flush flushes through writers that wrap the connection's, as long as
+// they implement Unwrap. Writers that can't flush are left buffering.
+func (c *Context) flush() error {
+	err := http.NewResponseController(c.W).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
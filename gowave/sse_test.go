@@ -0,0 +1,141 @@
+package gowave
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Reading event: %v", err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestContextSSEvent(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.UseHandlers(func(ctx *Context) {
+		ctx.W = &wrappedWriter{ctx.W}
+		ctx.Next()
+	})
+	release := make(chan struct{})
+	g.Get("/events", func(ctx *Context) {
+		next := 1
+		if id, err := strconv.Atoi(ctx.LastEventID()); err == nil {
+			next = id + 1
+		}
+		ctx.Stream(func(w io.Writer) bool {
+			_ = ctx.SSEventWithID(strconv.Itoa(next), "tick", map[string]int{"n": next})
+			if next == 1 {
+				<-release
+			}
+			next++
+			return next <= 3
+		})
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	// the handler blocks after the first event, so it must have been flushed
+	if got := readEvent(t, r); got != "id: 1\nevent: tick\ndata: {\"n\":1}\n" {
+		t.Errorf("Unexpected first event %q", got)
+	}
+	close(release)
+	for _, want := range []string{"id: 2\n", "id: 3\n"} {
+		if got := readEvent(t, r); !strings.HasPrefix(got, want) {
+			t.Errorf("Expected an event starting with %q, got %q", want, got)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/events", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "id: 3\nevent: tick\ndata: {\"n\":3}\n\n" {
+		t.Errorf("Expected the stream to resume after 2, got %q", body)
+	}
+}
+
+func TestContextStreamClientGone(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	gone := make(chan bool, 1)
+	g.Get("/stream", func(ctx *Context) {
+		gone <- ctx.Stream(func(w io.Writer) bool {
+			_ = ctx.SSEvent("", "ping")
+			time.Sleep(10 * time.Millisecond)
+			return true
+		})
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readEvent(t, bufio.NewReader(resp.Body)); got != "data: ping\n" {
+		t.Errorf("Unexpected event %q", got)
+	}
+	cancel()
+	resp.Body.Close()
+	select {
+	case clientGone := <-gone:
+		if !clientGone {
+			t.Error("Expected Stream to report the client as gone")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream did not stop after the client disconnected")
+	}
+}
+
+func TestContextSSEventLineBreaks(t *testing.T) {
+	engine := newTestEngine()
+	engine.Group("api").Get("/events", func(ctx *Context) {
+		_ = ctx.SSEvent("", "a\revent: x\r\nb\nc")
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+	want := "data: a\ndata: event: x\ndata: b\ndata: c\n\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected %q, got %q", want, w.Body.String())
+	}
+}
@@ -0,0 +1,137 @@
+package gowave
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// StatusCode is sent if the handler is still running when Timeout
+	// passes. Defaults to 503; 504 suits routes that mostly wait on
+	// upstream services.
+	StatusCode int
+}
+
+// Timeout answers with 503 if the handlers that follow it are still
+// running after d. See TimeoutWithConfig.
+func Timeout(d time.Duration) MiddlewareFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig runs the rest of the chain on its own goroutine and
+// sends the timeout status as soon as the timeout passes, even if the
+// handler ignores it. The request context, and so ctx itself, is cancelled
+// at that point; the handler should stop when ctx is done, since the
+// Context is only released once it returns. Until then its response is
+// buffered, and its writes after the timeout fail with
+// http.ErrHandlerTimeout, so streaming handlers should not use it. The
+// handler must write through ctx.W rather than ctx.Writer().
+func TimeoutWithConfig(conf TimeoutConfig) MiddlewareFunc {
+	if conf.StatusCode == 0 {
+		conf.StatusCode = http.StatusServiceUnavailable
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			c, cancel := context.WithTimeout(ctx.Req.Context(), conf.Timeout)
+			defer cancel()
+			req, w := ctx.Req, ctx.W
+			tw := &timeoutWriter{header: make(http.Header), status: http.StatusOK}
+			ctx.Req, ctx.W = req.WithContext(c), tw
+			done := make(chan struct{})
+			var panicked any
+			go func() {
+				defer close(done)
+				defer func() { panicked = recover() }()
+				next(ctx)
+			}()
+
+			timedOut := false
+			select {
+			case <-done:
+			case <-c.Done():
+				if errors.Is(c.Err(), context.DeadlineExceeded) {
+					timedOut = true
+					tw.timeout()
+					writeTimeout(w, conf.StatusCode)
+				}
+				<-done
+			}
+			ctx.Req, ctx.W = req, w
+			if panicked != nil {
+				// re-panicked on the request goroutine so that Recovery
+				// answers on the real writer instead of the discarded buffer
+				panic(panicked)
+			}
+			if timedOut {
+				ctx.Abort()
+				return
+			}
+			header := w.Header()
+			for k, v := range tw.header {
+				header[k] = v
+			}
+			w.WriteHeader(tw.status)
+			_, _ = w.Write(tw.buf.Bytes())
+		}
+	}
+}
+
+// writeTimeout sends the timeout response in full and flushes it, so that
+// the client gets it while the handler is still running.
+func writeTimeout(w http.ResponseWriter, code int) {
+	body := http.StatusText(code)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	_, _ = io.WriteString(w, body)
+	_ = http.NewResponseController(w).Flush()
+}
+
+// timeoutWriter buffers a response until the handler returns. It has no
+// Unwrap so that flushes and hijacks can't bypass the buffer.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader || code < 200 {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.buf.Write(b)
+}
+
+// timeout makes the handler's further writes fail.
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+}
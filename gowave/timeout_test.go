@@ -0,0 +1,136 @@
+package gowave
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupUser(ctx context.Context) string {
+	user, _ := ctx.Value("user").(string)
+	return user
+}
+
+func TestContextAsContext(t *testing.T) {
+	type traceKey struct{}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	parent, cancel := context.WithCancel(context.WithValue(req.Context(), traceKey{}, "t1"))
+	c := &Context{Req: req.WithContext(parent)}
+	c.Set("user", "bob")
+	var ctx context.Context = c
+	if lookupUser(ctx) != "bob" || ctx.Value(traceKey{}) != "t1" || ctx.Value("missing") != nil {
+		t.Errorf("Unexpected values %v %v", lookupUser(ctx), ctx.Value(traceKey{}))
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected a live context")
+	}
+	cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected Done to follow the request context")
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Expected Canceled, got %v", ctx.Err())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	wait := func(ctx *Context) {
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+			_ = ctx.String(http.StatusOK, "late")
+		}
+	}
+	g.Get("/slow", wait, Timeout(20*time.Millisecond))
+	g.Get("/upstream", wait, TimeoutWithConfig(TimeoutConfig{Timeout: 20 * time.Millisecond, StatusCode: http.StatusGatewayTimeout}))
+	g.Get("/fast", func(ctx *Context) {
+		if _, ok := ctx.Deadline(); !ok {
+			_ = ctx.String(http.StatusInternalServerError, "no deadline")
+			return
+		}
+		ctx.W.Header().Set("X-Handler", "fast")
+		_ = ctx.String(http.StatusCreated, "done")
+	}, Timeout(time.Second))
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/api/slow", http.StatusServiceUnavailable, "Service Unavailable"},
+		{"/api/upstream", http.StatusGatewayTimeout, "Gateway Timeout"},
+		{"/api/fast", http.StatusCreated, "done"},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.status, tt.body, w.Code, w.Body.String())
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("%s: took %v", tt.path, time.Since(start))
+		}
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/fast", nil))
+	if w.Header().Get("X-Handler") != "fast" || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Expected the buffered headers to be sent, got %v", w.Header())
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	g.Use(Recovery, Timeout(time.Second))
+	g.Get("/panic", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "partial") {
+		t.Errorf("Expected Recovery's 500 without the buffered body, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestTimeoutHandlerIgnoresContext(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	writeErr := make(chan error, 1)
+	release := make(chan struct{})
+	g.Get("/stuck", func(ctx *Context) {
+		<-release
+		_, err := ctx.W.Write([]byte("late"))
+		writeErr <- err
+	}, Timeout(20*time.Millisecond))
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	res, err := http.Get(srv.URL + "/api/stuck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || string(body) != "Service Unavailable" {
+		t.Errorf("Expected 503, got %d %q", res.StatusCode, body)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected the timeout response before the handler returns, took %v", time.Since(start))
+	}
+
+	release <- struct{}{}
+	if err := <-writeErr; err != http.ErrHandlerTimeout {
+		t.Errorf("Expected ErrHandlerTimeout for a write after the timeout, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package gowave
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultCertCheckInterval = time.Second
+
+// certReloader serves a certificate loaded from disk and reloads it once
+// the cert or key file changes. Files are checked during handshakes, at
+// most once per checkInterval.
+type certReloader struct {
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	stamp   [2]fileStamp
+	checked time.Time
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, checkInterval: defaultCertCheckInterval}
+	stamp, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(stamp); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) >= r.checkInterval {
+		r.checked = time.Now()
+		// a failed reload keeps serving the previous certificate, the files
+		// may be caught halfway through a rotation
+		if stamp, err := r.stat(); err == nil && stamp != r.stamp {
+			_ = r.load(stamp)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) stat() ([2]fileStamp, error) {
+	var stamp [2]fileStamp
+	for i, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return stamp, err
+		}
+		stamp[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamp, nil
+}
+
+func (r *certReloader) load(stamp [2]fileStamp) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.stamp = stamp
+	return nil
+}
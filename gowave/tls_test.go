@@ -0,0 +1,193 @@
+package gowave
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// writeSelfSignedCert writes a fresh self-signed certificate for
+// 127.0.0.1 with the given common name to certFile and keyFile.
+func writeSelfSignedCert(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func peerCommonName(t *testing.T, addr string) string {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSignedCert(t, certFile, keyFile, "first")
+
+	engine := newTestEngine()
+	engine.HttpConfig.TLS.Reload = true
+	addr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	stopped := make(chan struct{})
+	go func() {
+		engine.RunWithTLS(addr, certFile, keyFile)
+		close(stopped)
+	}()
+	waitForServer(t, addr)
+	if cn := peerCommonName(t, addr); cn != "first" {
+		t.Fatalf("Expected first, got %s", cn)
+	}
+
+	writeSelfSignedCert(t, certFile, keyFile, "second")
+	deadline := time.Now().Add(5 * time.Second)
+	for peerCommonName(t, addr) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("the rotated certificate was not picked up")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+}
+
+func TestCertReloaderKeepsCertOnBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSignedCert(t, certFile, keyFile, "first")
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.checkInterval = 0
+	if err := os.WriteFile(keyFile, []byte("rotating"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := r.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("Expected the previous certificate, got %v %v", cert, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil || leaf.Subject.CommonName != "first" {
+		t.Errorf("Expected first, got %v %v", leaf, err)
+	}
+}
+
+func TestEngineRunH2C(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("")
+	g.Get("/proto", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, ctx.Req.Proto)
+	})
+	addr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	stopped := make(chan struct{})
+	go func() {
+		engine.RunH2C(addr)
+		close(stopped)
+	}()
+	waitForServer(t, addr)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + addr + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+		t.Errorf("Expected HTTP/2.0, got %s %q", resp.Proto, body)
+	}
+	resp, err = http.Get("http://" + addr + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "HTTP/1.1" {
+		t.Errorf("Expected HTTP/1.1 to keep working, got %q", body)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+}
+
+func TestEngineRunTLSFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSignedCert(t, certFile, keyFile, "config")
+
+	engine := newTestEngine()
+	engine.HttpConfig.TLS.CertFile = certFile
+	engine.HttpConfig.TLS.KeyFile = keyFile
+	addrs := []string{
+		fmt.Sprintf("127.0.0.1:%d", freePort(t)),
+		fmt.Sprintf("127.0.0.1:%d", freePort(t)),
+	}
+	stopped := make(chan struct{})
+	go func() {
+		engine.Run(addrs...)
+		close(stopped)
+	}()
+	for _, addr := range addrs {
+		waitForServer(t, addr)
+		if cn := peerCommonName(t, addr); cn != "config" {
+			t.Errorf("%s: expected config, got %s", addr, cn)
+		}
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+}
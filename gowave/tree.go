@@ -0,0 +1,267 @@
+package gowave
+
+import (
+	"fmt"
+	"strings"
+)
+
+// methodTree is the routing tree of a single HTTP method.
+type methodTree struct {
+	method string
+	root   *treeNode
+}
+
+type methodTrees []methodTree
+
+func (trees methodTrees) get(method string) *treeNode {
+	for _, tree := range trees {
+		if tree.method == method {
+			return tree.root
+		}
+	}
+	return nil
+}
+
+// treeNode is a node of a compressed radix tree. Static text is stored as
+// byte prefixes shared between routes and may span several path segments;
+// params, * and ** always occupy a whole segment of their own.
+type treeNode struct {
+	segment
+	prefix   string      // static text, or the raw pattern segment of a dynamic node
+	pattern  string      // route pattern from the root up to and including this node
+	statics  []*treeNode // static children, no two share a first byte
+	dynamics []*treeNode // dynamic children ordered by rank
+	route    *route      // nil unless a route ends here
+}
+
+// treeToken is either a run of static text or a single dynamic segment.
+type treeToken struct {
+	text string
+	seg  segment
+}
+
+func tokenize(pattern string) ([]treeToken, error) {
+	strList := strings.Split(pattern, "/")
+	tokens := make([]treeToken, 0, len(strList))
+	static := ""
+	for idx, str := range strList {
+		if idx == 0 {
+			continue
+		}
+		static += "/"
+		seg, err := parseSegment(str, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if seg.kind == kindStatic {
+			static += str
+			continue
+		}
+		if seg.kind == kindCatchAll && idx < len(strList)-1 {
+			return nil, fmt.Errorf("route %s: ** must be the last segment", pattern)
+		}
+		tokens = append(tokens, treeToken{text: static}, treeToken{text: str, seg: seg})
+		static = ""
+	}
+	if static != "" {
+		tokens = append(tokens, treeToken{text: static})
+	}
+	return tokens, nil
+}
+
+// addRoute returns the node at which pattern ends, creating it if needed.
+// Patterns that would shadow an existing sibling segment are rejected
+// before the tree is modified.
+func (n *treeNode) addRoute(pattern string) (*treeNode, error) {
+	tokens, err := tokenize(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if c := n.conflict(tokens); c != nil {
+		return nil, fmt.Errorf("route %s conflicts with %s", pattern, c.pattern)
+	}
+	cur := n
+	for _, tok := range tokens {
+		if tok.seg.kind == kindStatic {
+			cur = cur.insertStatic(tok.text)
+		} else {
+			cur = cur.insertDynamic(tok)
+		}
+	}
+	return cur, nil
+}
+
+func (n *treeNode) conflict(tokens []treeToken) *treeNode {
+	cur := n
+	for _, tok := range tokens {
+		if tok.seg.kind == kindStatic {
+			for s := tok.text; s != ""; {
+				child := cur.staticChild(s[0])
+				if child == nil || !strings.HasPrefix(s, child.prefix) {
+					return nil
+				}
+				s = s[len(child.prefix):]
+				cur = child
+			}
+			continue
+		}
+		var next *treeNode
+		for _, child := range cur.dynamics {
+			if child.prefix == tok.text {
+				next = child
+				break
+			}
+			if tok.seg.conflicts(&child.segment) {
+				return child
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		cur = next
+	}
+	return nil
+}
+
+func (n *treeNode) staticChild(c byte) *treeNode {
+	for _, child := range n.statics {
+		if child.prefix[0] == c {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *treeNode) insertStatic(s string) *treeNode {
+	cur := n
+	for s != "" {
+		child := cur.staticChild(s[0])
+		if child == nil {
+			child = &treeNode{prefix: s, pattern: cur.pattern + s}
+			cur.statics = append(cur.statics, child)
+			return child
+		}
+		l := commonPrefixLen(child.prefix, s)
+		if l < len(child.prefix) {
+			child.split(l)
+		}
+		s = s[l:]
+		cur = child
+	}
+	return cur
+}
+
+// split keeps the first l bytes of n's prefix in n and moves the rest,
+// along with n's children and route, into a new static child.
+func (n *treeNode) split(l int) {
+	rest := *n
+	rest.prefix = n.prefix[l:]
+	*n = treeNode{
+		prefix:  n.prefix[:l],
+		pattern: n.pattern[:len(n.pattern)-len(rest.prefix)],
+		statics: []*treeNode{&rest},
+	}
+}
+
+func (n *treeNode) insertDynamic(tok treeToken) *treeNode {
+	for _, child := range n.dynamics {
+		if child.prefix == tok.text {
+			return child
+		}
+	}
+	child := &treeNode{segment: tok.seg, prefix: tok.text, pattern: n.pattern + tok.text}
+	i := len(n.dynamics)
+	for i > 0 && n.dynamics[i-1].rank() > child.rank() {
+		i--
+	}
+	n.dynamics = append(n.dynamics, nil)
+	copy(n.dynamics[i+1:], n.dynamics[i:])
+	n.dynamics[i] = child
+	return child
+}
+
+// lookup finds the route matching path, appending bound values to params.
+// Static text wins over params, params over * and * over **; a branch
+// that dead-ends falls back to the next candidate. It never writes to the
+// tree, so it is safe for concurrent use once routes are registered.
+func (n *treeNode) lookup(path string, params *Params) *treeNode {
+	if path == "" {
+		if n.route != nil {
+			return n
+		}
+	} else if child := n.staticChild(path[0]); child != nil && strings.HasPrefix(path, child.prefix) {
+		if node := child.lookup(path[len(child.prefix):], params); node != nil {
+			return node
+		}
+	}
+	for _, child := range n.dynamics {
+		switch child.kind {
+		case kindParam, kindWildcard:
+			seg, rest := path, ""
+			if i := strings.IndexByte(path, '/'); i >= 0 {
+				seg, rest = path[:i], path[i:]
+			}
+			if seg == "" || child.matcher != nil && !child.matcher(seg) {
+				continue
+			}
+			*params = append(*params, Param{Key: child.key, Value: seg})
+			if node := child.lookup(rest, params); node != nil {
+				return node
+			}
+			*params = (*params)[:len(*params)-1]
+		case kindCatchAll:
+			if child.route != nil {
+				*params = append(*params, Param{Key: child.key, Value: path})
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// lookupFold is a case-insensitive lookup that appends the registered
+// spelling of the matched path to buf, or returns nil if nothing matches.
+func (n *treeNode) lookupFold(path string, buf []byte) []byte {
+	if path == "" {
+		if n.route != nil {
+			return buf
+		}
+	} else {
+		for _, child := range n.statics {
+			l := len(child.prefix)
+			if len(path) >= l && strings.EqualFold(path[:l], child.prefix) {
+				if out := child.lookupFold(path[l:], append(buf, child.prefix...)); out != nil {
+					return out
+				}
+			}
+		}
+	}
+	for _, child := range n.dynamics {
+		switch child.kind {
+		case kindParam, kindWildcard:
+			seg, rest := path, ""
+			if i := strings.IndexByte(path, '/'); i >= 0 {
+				seg, rest = path[:i], path[i:]
+			}
+			if seg == "" || child.matcher != nil && !child.matcher(seg) {
+				continue
+			}
+			if out := child.lookupFold(rest, append(buf, seg...)); out != nil {
+				return out
+			}
+		case kindCatchAll:
+			if child.route != nil {
+				return append(buf, path...)
+			}
+		}
+	}
+	return nil
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
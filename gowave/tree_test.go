@@ -0,0 +1,160 @@
+package gowave
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newTestTree(t *testing.T, patterns ...string) *treeNode {
+	root := &treeNode{}
+	for _, pattern := range patterns {
+		node, err := root.addRoute(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.route = &route{}
+	}
+	return root
+}
+
+func TestTreeLookup(t *testing.T) {
+	root := newTestTree(t,
+		"/",
+		"/users",
+		"/users/new",
+		"/users/:id",
+		"/users/:id/profile",
+		"/users2/{id:int}",
+		"/teams/*",
+		"/teams/**",
+		"/static/**",
+		"/api/v1/orders/{id:int}",
+		"/api/v1/orders/{code:[a-z]{3}}",
+	)
+	tests := []struct {
+		path, pattern string
+		params        Params
+	}{
+		{"/", "/", nil},
+		{"/users", "/users", nil},
+		{"/users/new", "/users/new", nil},
+		{"/users/42", "/users/:id", Params{{"id", "42"}}},
+		{"/users/new/profile", "/users/:id/profile", Params{{"id", "new"}}},
+		{"/users2/7", "/users2/{id:int}", Params{{"id", "7"}}},
+		{"/users2/x", "", nil},
+		{"/teams/a", "/teams/*", Params{{"*", "a"}}},
+		{"/teams/a/b", "/teams/**", Params{{"**", "a/b"}}},
+		{"/static/css/main.css", "/static/**", Params{{"**", "css/main.css"}}},
+		{"/static", "", nil},
+		{"/api/v1/orders/12", "/api/v1/orders/{id:int}", Params{{"id", "12"}}},
+		{"/api/v1/orders/abc", "/api/v1/orders/{code:[a-z]{3}}", Params{{"code", "abc"}}},
+		{"/api/v1/orders/abcd", "", nil},
+		{"/api/v2", "", nil},
+	}
+	for _, tt := range tests {
+		var params Params
+		node := root.lookup(tt.path, &params)
+		if tt.pattern == "" {
+			if node != nil {
+				t.Errorf("%s: expected no match, got %s", tt.path, node.pattern)
+			}
+			continue
+		}
+		if node == nil || node.pattern != tt.pattern {
+			t.Errorf("%s: expected %s, got %v", tt.path, tt.pattern, node)
+			continue
+		}
+		if fmt.Sprint(params) != fmt.Sprint(tt.params) {
+			t.Errorf("%s: expected params %v, got %v", tt.path, tt.params, params)
+		}
+	}
+}
+
+func TestTreeConflict(t *testing.T) {
+	root := newTestTree(t, "/users/:id")
+	for _, pattern := range []string{"/users/:name/posts", "/users/*", "/users/**/edit"} {
+		if _, err := root.addRoute(pattern); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+	var params Params
+	if node := root.lookup("/users/1/posts", &params); node != nil {
+		t.Errorf("Expected the rejected route not to be registered, got %s", node.pattern)
+	}
+}
+
+func benchmarkRoutes(n int) []string {
+	routes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		routes = append(routes, fmt.Sprintf("/g%d/resource%d/:id", i/10, i))
+	}
+	return routes
+}
+
+func benchmarkPaths(n int) []string {
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		paths = append(paths, fmt.Sprintf("/g%d/resource%d/42", i/10, i))
+	}
+	return paths
+}
+
+// BenchmarkLookup compares the per-group linear scan the engine used to do,
+// a single segment trie and the radix tree the engine uses now.
+func BenchmarkLookup(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		routes, paths := benchmarkRoutes(n), benchmarkPaths(n)
+
+		type group struct {
+			prefix string
+			trie   *Trie
+		}
+		var groups []group
+		for i, r := range routes {
+			if i%10 == 0 {
+				groups = append(groups, group{prefix: fmt.Sprintf("/g%d", i/10), trie: NewTrie()})
+			}
+			_ = groups[len(groups)-1].trie.Put(TrimPrefix(r, groups[len(groups)-1].prefix), "")
+		}
+		b.Run(fmt.Sprintf("groups/%d", n), func(b *testing.B) {
+			params := make(Params, 0, defaultParamsCap)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				path := paths[i%n]
+				for _, g := range groups {
+					params = params[:0]
+					if g.trie.Lookup(TrimPrefix(path, g.prefix), &params) != nil {
+						break
+					}
+				}
+			}
+		})
+
+		trie := NewTrie()
+		for _, r := range routes {
+			_ = trie.Put(r, "")
+		}
+		b.Run(fmt.Sprintf("trie/%d", n), func(b *testing.B) {
+			params := make(Params, 0, defaultParamsCap)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				params = params[:0]
+				trie.Lookup(paths[i%n], &params)
+			}
+		})
+
+		root := &treeNode{}
+		for _, r := range routes {
+			node, _ := root.addRoute(r)
+			node.route = &route{}
+		}
+		b.Run(fmt.Sprintf("radix/%d", n), func(b *testing.B) {
+			params := make(Params, 0, defaultParamsCap)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				params = params[:0]
+				root.lookup(paths[i%n], &params)
+			}
+		})
+	}
+}
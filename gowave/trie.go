@@ -1,6 +1,30 @@
 package gowave
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
+
+type Param struct {
+	Key   string
+	Value string
+}
+
+type Params []Param
+
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
 
 type Trie struct {
 	Root *Node
@@ -12,11 +36,11 @@ func NewTrie() *Trie {
 	}
 }
 
-func (t *Trie) Put(text string, name string) {
+func (t *Trie) Put(text string, name string) error {
 	if t.Root == nil {
 		t.Root = &Node{text: "/", children: make([]*Node, 0)}
 	}
-	t.Root.Put(text, name)
+	return t.Root.Put(text, name)
 }
 
 func (t *Trie) Get(text string) *Node {
@@ -26,7 +50,103 @@ func (t *Trie) Get(text string) *Node {
 	return t.Root.Get(text)
 }
 
+func (t *Trie) Match(text string) (*Node, Params) {
+	if t.Root == nil {
+		return nil, nil
+	}
+	return t.Root.Match(text)
+}
+
+func (t *Trie) Lookup(text string, params *Params) *Node {
+	if t.Root == nil {
+		return nil
+	}
+	return t.Root.Lookup(text, params)
+}
+
+type nodeKind uint8
+
+// node kinds in match priority order
+const (
+	kindStatic nodeKind = iota
+	kindParam
+	kindWildcard
+	kindCatchAll
+)
+
+// segment is the parsed form of one "/"-separated piece of a route pattern.
+type segment struct {
+	kind       nodeKind
+	key        string          // param name bound by this segment
+	constraint string          // constraint source of a {name:constraint} segment
+	matcher    ParamConstraint // nil for an unconstrained param
+}
+
+// parseSegment parses a single pattern segment: "name" is static, ":name"
+// and "{name}" are params, "{name:int}" or "{name:[a-z]+}" are constrained
+// params, "*" matches any one segment and "**" the rest of the path.
+func parseSegment(text string, routerName string) (segment, error) {
+	var seg segment
+	switch {
+	case text == "**":
+		seg.kind, seg.key = kindCatchAll, "**"
+	case text == "*":
+		seg.kind, seg.key = kindWildcard, "*"
+	case strings.HasPrefix(text, ":"):
+		seg.kind, seg.key = kindParam, text[1:]
+	case strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}"):
+		seg.kind, seg.key = kindParam, text[1:len(text)-1]
+		if i := strings.Index(seg.key, ":"); i >= 0 {
+			seg.key, seg.constraint = seg.key[:i], seg.key[i+1:]
+			matcher, err := compileConstraint(seg.constraint)
+			if err != nil {
+				return seg, fmt.Errorf("route %s: %w", routerName, err)
+			}
+			seg.matcher = matcher
+		}
+	default:
+		seg.kind = kindStatic
+	}
+	if seg.kind == kindParam && seg.key == "" {
+		return seg, fmt.Errorf("route %s: param name is empty", routerName)
+	}
+	return seg, nil
+}
+
+// rank orders siblings for matching; constrained params are tried before
+// a plain param so that the plain one only catches what they reject.
+func (s *segment) rank() int {
+	switch s.kind {
+	case kindStatic:
+		return 0
+	case kindParam:
+		if s.matcher != nil {
+			return 1
+		}
+		return 2
+	case kindWildcard:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// conflicts reports whether two sibling segments would match the same
+// single path segment with equal priority, or one would always shadow the
+// other. Params with different constraints may share a position.
+func (s *segment) conflicts(o *segment) bool {
+	single := func(seg *segment) bool { return seg.kind == kindParam || seg.kind == kindWildcard }
+	if !single(s) || !single(o) {
+		return false
+	}
+	if s.matcher != nil && o.matcher != nil {
+		return s.constraint == o.constraint
+	}
+	return s.matcher == nil && o.matcher == nil
+}
+
 type Node struct {
+	segment
 	Name       string
 	text       string
 	routerName string
@@ -34,67 +154,124 @@ type Node struct {
 	isEnd      bool
 }
 
-func (n *Node) Put(text string, name string) {
-	cur := n
+func (n *Node) Put(text string, name string) error {
 	strList := strings.Split(text, "/")
+	for idx, str := range strList {
+		if idx > 0 && idx < len(strList)-1 && str == "**" {
+			return fmt.Errorf("route %s: ** must be the last segment", text)
+		}
+	}
+	cur := n
 	routerName := ""
 	for idx, str := range strList {
 		if idx == 0 {
 			continue
 		}
-		children := cur.children
-		isMatch := false
-		for _, child := range children {
+		routerName += "/" + str
+		var next *Node
+		for _, child := range cur.children {
 			if child.text == str {
-				routerName += "/" + child.text
-				isMatch = true
-				cur = child
+				next = child
 				break
 			}
 		}
-		if !isMatch {
-			isEnd := false
-			if idx == len(strList)-1 {
-				isEnd = true
+		if next == nil {
+			seg, err := parseSegment(str, routerName)
+			if err != nil {
+				return err
 			}
-			routerName += "/" + str
-			node := &Node{text: str, routerName: routerName, children: make([]*Node, 0), isEnd: isEnd, Name: name}
-			children = append(children, node)
-			cur.children = children
-			cur = node
+			node := &Node{segment: seg, text: str, routerName: routerName, children: make([]*Node, 0), Name: name}
+			for _, child := range cur.children {
+				if node.conflicts(&child.segment) {
+					return fmt.Errorf("route %s conflicts with %s", text, child.routerName)
+				}
+			}
+			next = node
+			cur.insertChild(next)
 		}
+		cur = next
+	}
+	cur.isEnd = true
+	cur.Name = name
+	return nil
+}
+
+// insertChild keeps children ordered by rank so that a single pass over
+// them tries static, param, wildcard and catch-all segments in that order.
+func (n *Node) insertChild(child *Node) {
+	i := len(n.children)
+	for i > 0 && n.children[i-1].rank() > child.rank() {
+		i--
 	}
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
 }
 
 func (n *Node) Get(text string) *Node {
-	cur := n
-	strList := strings.Split(text, "/")
-	routerName := ""
-	for idx, str := range strList {
-		if idx == 0 {
-			continue
+	node, _ := n.Match(text)
+	return node
+}
+
+// Match looks up text like Get and also returns the values bound to the
+// :param, * and ** segments of the matched route. Static segments take
+// precedence over params, params over *, and * over **; a branch that
+// dead-ends falls back to the next candidate.
+func (n *Node) Match(text string) (*Node, Params) {
+	var params Params
+	node := n.Lookup(text, &params)
+	if node == nil {
+		return nil, nil
+	}
+	return node, params
+}
+
+// Lookup is the read-only form of Match used when serving requests: it
+// never writes to the trie and appends the bound values to params, so a
+// caller that reuses params does not allocate.
+func (n *Node) Lookup(text string, params *Params) *Node {
+	i := strings.IndexByte(text, '/')
+	if i < 0 {
+		return n.match("", false, params)
+	}
+	return n.match(text[i+1:], true, params)
+}
+
+// match consumes the next segment of path; hasSeg is false once every
+// segment has been consumed, which tells "" apart from an empty segment.
+func (n *Node) match(path string, hasSeg bool, params *Params) *Node {
+	if !hasSeg {
+		if n.isEnd {
+			return n
 		}
-		children := cur.children
-		isMatch := false
-		for _, child := range children {
-			if child.text == str || child.text == "*" || strings.Contains(child.text, ":") {
-				isMatch = true
-				routerName += "/" + child.text
-				child.routerName = routerName
-				cur = child
-				if idx == len(strList)-1 {
-					return child
-				}
-				break
+		return nil
+	}
+	seg, rest, more := path, "", false
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		seg, rest, more = path[:i], path[i+1:], true
+	}
+	for _, child := range n.children {
+		switch child.kind {
+		case kindStatic:
+			if child.text != seg {
+				continue
 			}
-		}
-		if !isMatch {
-			for _, child := range children {
-				if child.text == "**" {
-					routerName += "/" + child.text
-					child.routerName = routerName
-					return child
-				}
+			if node := child.match(rest, more, params); node != nil {
+				return node
+			}
+		case kindParam, kindWildcard:
+			if child.matcher != nil && !child.matcher(seg) {
+				continue
+			}
+			*params = append(*params, Param{Key: child.key, Value: seg})
+			if node := child.match(rest, more, params); node != nil {
+				return node
+			}
+			*params = (*params)[:len(*params)-1]
+		case kindCatchAll:
+			if child.isEnd {
+				*params = append(*params, Param{Key: child.key, Value: path})
+				return child
 			}
 		}
 	}
@@ -1,15 +1,17 @@
 package gowave
 
 import (
+	"strconv"
+	"sync"
 	"testing"
 )
 
 func TestTrie(t *testing.T) {
 	root := &Node{text: "/", children: make([]*Node, 0)}
-	root.Put("/api/user/:id")
-	root.Put("/api/info/hello")
-	root.Put("/api/info/test")
-	root.Put("/api/order/*")
+	root.Put("/api/user/:id", "")
+	root.Put("/api/info/hello", "")
+	root.Put("/api/info/test", "")
+	root.Put("/api/order/*", "")
 
 	if root.Get("/api/user/123").text != ":id" {
 		t.Errorf("Expected :id, got %s", root.Get("/api/user/123").text)
@@ -24,3 +26,206 @@ func TestTrie(t *testing.T) {
 		t.Errorf("Expected *, got %s", root.Get("/api/order/123").text)
 	}
 }
+
+func TestTrieParams(t *testing.T) {
+	root := &Node{text: "/", children: make([]*Node, 0)}
+	root.Put("/api/user/:id/post/:pid", "")
+	root.Put("/api/order/*", "")
+	root.Put("/static/**", "")
+
+	_, params := root.Match("/api/user/42/post/7")
+	if params.ByName("id") != "42" || params.ByName("pid") != "7" {
+		t.Errorf("Expected id=42 pid=7, got %v", params)
+	}
+	_, params = root.Match("/api/order/abc")
+	if params.ByName("*") != "abc" {
+		t.Errorf("Expected *=abc, got %v", params)
+	}
+	node, params := root.Match("/static/css/site/main.css")
+	if node == nil || node.text != "**" {
+		t.Fatalf("Expected ** node, got %v", node)
+	}
+	if params.ByName("**") != "css/site/main.css" {
+		t.Errorf("Expected **=css/site/main.css, got %v", params)
+	}
+	if _, ok := params.Get("id"); ok {
+		t.Errorf("Expected no id param, got %v", params)
+	}
+}
+
+func TestTriePriority(t *testing.T) {
+	root := &Node{text: "/", children: make([]*Node, 0)}
+	root.Put("/files/**", "")
+	root.Put("/teams/*", "")
+	root.Put("/teams/**", "")
+	root.Put("/users/:id", "")
+	root.Put("/users/new", "")
+	root.Put("/users/new/profile/edit", "")
+
+	if node := root.Get("/users/new"); node == nil || node.routerName != "/users/new" {
+		t.Errorf("Expected /users/new, got %v", node)
+	}
+	if node := root.Get("/users/42"); node == nil || node.routerName != "/users/:id" {
+		t.Errorf("Expected /users/:id, got %v", node)
+	}
+	if node := root.Get("/users/new/avatar"); node != nil {
+		t.Errorf("Expected no match, got %s", node.routerName)
+	}
+	// the static branch dead-ends, so the lookup backtracks to :id
+	root.Put("/users/:id/profile", "")
+	node, params := root.Match("/users/new/profile")
+	if node == nil || node.routerName != "/users/:id/profile" || params.ByName("id") != "new" {
+		t.Errorf("Expected /users/:id/profile with id=new, got %v %v", node, params)
+	}
+	if node := root.Get("/teams/a"); node == nil || node.routerName != "/teams/*" {
+		t.Errorf("Expected /teams/*, got %v", node)
+	}
+	if node := root.Get("/teams/a/b"); node == nil || node.routerName != "/teams/**" {
+		t.Errorf("Expected /teams/**, got %v", node)
+	}
+	if node := root.Get("/files"); node != nil {
+		t.Errorf("Expected no match, got %s", node.routerName)
+	}
+}
+
+func TestTrieConflict(t *testing.T) {
+	root := &Node{text: "/", children: make([]*Node, 0)}
+	if err := root.Put("/users/:id", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Put("/users/:name/posts", ""); err == nil {
+		t.Error("Expected conflict between :id and :name")
+	}
+	if err := root.Put("/users/*", ""); err == nil {
+		t.Error("Expected conflict between :id and *")
+	}
+	if err := root.Put("/users/**/edit", ""); err == nil {
+		t.Error("Expected error for ** before the last segment")
+	}
+	if err := root.Put("/users/**", ""); err != nil {
+		t.Errorf("Expected no conflict, got %v", err)
+	}
+	if node := root.Get("/users/1/posts"); node != nil && node.routerName != "/users/**" {
+		t.Errorf("Expected the rejected route not to be registered, got %s", node.routerName)
+	}
+}
+
+func newBenchTrie() *Trie {
+	trie := NewTrie()
+	trie.Put("/api/user/:id", "")
+	trie.Put("/api/user/:id/post/:pid", "")
+	trie.Put("/api/info/hello", "")
+	trie.Put("/api/order/*", "")
+	trie.Put("/static/**", "")
+	return trie
+}
+
+func TestTrieLookupAllocs(t *testing.T) {
+	trie := newBenchTrie()
+	params := make(Params, 0, defaultParamsCap)
+	allocs := testing.AllocsPerRun(100, func() {
+		params = params[:0]
+		if trie.Lookup("/api/user/42/post/7", &params) == nil {
+			t.Fatal("Expected a match")
+		}
+		params = params[:0]
+		if trie.Lookup("/static/css/main.css", &params) == nil {
+			t.Fatal("Expected a match")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations per lookup, got %v", allocs)
+	}
+}
+
+func TestTrieConcurrentLookup(t *testing.T) {
+	trie := newBenchTrie()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			params := make(Params, 0, defaultParamsCap)
+			for j := 0; j < 1000; j++ {
+				id := strconv.Itoa(i*1000 + j)
+				params = params[:0]
+				node := trie.Lookup("/api/user/"+id+"/post/"+id, &params)
+				if node == nil || node.routerName != "/api/user/:id/post/:pid" {
+					t.Errorf("Expected /api/user/:id/post/:pid, got %v", node)
+					return
+				}
+				if params.ByName("id") != id || params.ByName("pid") != id {
+					t.Errorf("Expected id=pid=%s, got %v", id, params)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkTrieLookup(b *testing.B) {
+	trie := newBenchTrie()
+	params := make(Params, 0, defaultParamsCap)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		trie.Lookup("/api/user/42/post/7", &params)
+	}
+}
+
+func BenchmarkTrieLookupParallel(b *testing.B) {
+	trie := newBenchTrie()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		params := make(Params, 0, defaultParamsCap)
+		for pb.Next() {
+			params = params[:0]
+			trie.Lookup("/api/user/42/post/7", &params)
+		}
+	})
+}
+
+func TestTrieConstraints(t *testing.T) {
+	root := &Node{text: "/", children: make([]*Node, 0)}
+	for _, path := range []string{
+		"/orders/{id:int}",
+		"/orders/{code:[a-z]{3}}",
+		"/orders/:any",
+		"/files/{name:[a-z0-9_-]+}",
+		"/v/{ver:uuid}",
+	} {
+		if err := root.Put(path, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		path, route, key, value string
+	}{
+		{"/orders/42", "/orders/{id:int}", "id", "42"},
+		{"/orders/abc", "/orders/{code:[a-z]{3}}", "code", "abc"},
+		{"/orders/abcd", "/orders/:any", "any", "abcd"},
+		{"/files/report_2024-01", "/files/{name:[a-z0-9_-]+}", "name", "report_2024-01"},
+		{"/files/Report.txt", "", "", ""},
+		{"/v/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "/v/{ver:uuid}", "ver", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"/v/6ba7b810", "", "", ""},
+	}
+	for _, tt := range tests {
+		node, params := root.Match(tt.path)
+		if tt.route == "" {
+			if node != nil {
+				t.Errorf("%s: expected no match, got %s", tt.path, node.routerName)
+			}
+			continue
+		}
+		if node == nil || node.routerName != tt.route || params.ByName(tt.key) != tt.value {
+			t.Errorf("%s: expected %s with %s=%s, got %v %v", tt.path, tt.route, tt.key, tt.value, node, params)
+		}
+	}
+	if err := root.Put("/orders/{n:int}", ""); err == nil {
+		t.Error("Expected conflict between {id:int} and {n:int}")
+	}
+	if err := root.Put("/bad/{id:[a-z}", ""); err == nil {
+		t.Error("Expected error for an invalid regular expression")
+	}
+}
@@ -1,6 +1,7 @@
 package gowave
 
 import (
+	"path"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -13,6 +14,38 @@ func TrimPrefix(str string, prefix string) string {
 	return str
 }
 
+// joinPaths appends relativePath to absolutePath with exactly one slash
+// between them, keeping a trailing slash on relativePath.
+func joinPaths(absolutePath, relativePath string) string {
+	if relativePath == "" {
+		if absolutePath == "" {
+			return "/"
+		}
+		return absolutePath
+	}
+	return strings.TrimSuffix(absolutePath, "/") + "/" + strings.TrimPrefix(relativePath, "/")
+}
+
+// cleanPath removes . and .. elements and repeated slashes from p like
+// path.Clean, but keeps a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+func toggleTrailingSlash(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
 func IsASCII(s string) bool {
 	for _, r := range s {
 		if r > unicode.MaxASCII {
@@ -0,0 +1,23 @@
+package gowave
+
+import (
+	"net/http"
+
+	"github.com/ChenGuo505/gowave/websocket"
+)
+
+// Upgrade performs the WebSocket handshake with the engine's Upgrader and
+// takes over the connection. On failure the error response has already
+// been written. The handler owns the returned connection and must close it.
+func (c *Context) Upgrade() (*websocket.Conn, error) {
+	upgrader := &websocket.Upgrader{}
+	if c.engine != nil {
+		upgrader = &c.engine.Upgrader
+	}
+	conn, err := upgrader.Upgrade(c.W, c.Req, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.writer.setStatus(http.StatusSwitchingProtocols)
+	return conn, nil
+}
@@ -0,0 +1,401 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ChenGuo505/gowave/codec"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	maxControlPayload = 125
+	// payloadChunk is the largest payload buffer allocated before the data
+	// has arrived.
+	payloadChunk = 64 << 10
+)
+
+// DefaultReadLimit is the read limit of connections from an Upgrader
+// without ReadLimit and of dialed connections.
+const DefaultReadLimit = 32 << 20
+
+// Close codes defined by RFC 6455, section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+var (
+	ErrReadLimit = errors.New("websocket: message exceeds the read limit")
+	ErrCloseSent = errors.New("websocket: close frame already sent")
+)
+
+// CloseError is returned by ReadMessage once the peer has closed the
+// connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Text)
+}
+
+// Conn is a message-oriented WebSocket connection. One goroutine may read
+// while others write; writes are serialized. Pings are answered while
+// reading, so an application must keep reading to see control frames.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+
+	readLimit   int64
+	pongHandler func(data string) error
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool, readLimit int64) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{conn: conn, br: br, isServer: isServer, readLimit: readLimit}
+}
+
+// Subprotocol returns the subprotocol agreed on in the handshake.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the maximum size of a message. A larger message closes
+// the connection with CloseMessageTooBig. Zero means no limit.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPongHandler sets the function called with the payload of each pong.
+// A returned error is returned by ReadMessage.
+func (c *Conn) SetPongHandler(h func(data string) error) {
+	c.pongHandler = h
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// ReadMessage reads the next text or binary message, joining fragments.
+// Pings are answered and pongs passed to the pong handler on the way. When
+// the peer closes, the close is acknowledged and a *CloseError returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ     MessageType
+		message []byte
+	)
+	for {
+		h, err := c.readFrameHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+		if h.rsv != 0 {
+			return 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+		}
+		if h.masked != c.isServer {
+			return 0, nil, c.fail(CloseProtocolError, "bad masking")
+		}
+		switch h.opcode {
+		case opClose, opPing, opPong:
+			if !h.fin || h.length > maxControlPayload {
+				return 0, nil, c.fail(CloseProtocolError, "bad control frame")
+			}
+			payload, err := c.readPayload(h)
+			if err != nil {
+				return 0, nil, err
+			}
+			if err := c.handleControl(h.opcode, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		case opText, opBinary:
+			if typ != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			typ = MessageType(h.opcode)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+		if c.readLimit > 0 && int64(len(message))+h.length > c.readLimit {
+			_ = c.fail(CloseMessageTooBig, "")
+			return 0, nil, ErrReadLimit
+		}
+		payload, err := c.readPayload(h)
+		if err != nil {
+			return 0, nil, err
+		}
+		message = append(message, payload...)
+		if h.fin {
+			if typ == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8")
+			}
+			return typ, message, nil
+		}
+	}
+}
+
+// ReadJSON reads the next message and decodes it into v.
+func (c *Conn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return codec.JSON.Unmarshal(data, v)
+}
+
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("websocket: bad message type %d", typ)
+	}
+	return c.writeFrame(byte(typ), data)
+}
+
+func (c *Conn) WriteJSON(v any) error {
+	data, err := codec.JSON.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, data)
+}
+
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control payload too large")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a normal close frame and closes the connection.
+func (c *Conn) Close() error {
+	return c.CloseWithStatus(CloseNormalClosure, "")
+}
+
+// CloseWithStatus sends a close frame with code and reason, then closes
+// the connection.
+func (c *Conn) CloseWithStatus(code int, reason string) error {
+	err := c.writeClose(code, reason)
+	if closeErr := c.conn.Close(); err == nil || errors.Is(err, ErrCloseSent) {
+		err = closeErr
+	}
+	return err
+}
+
+func (c *Conn) handleControl(opcode byte, payload []byte) error {
+	switch opcode {
+	case opPing:
+		if err := c.writeFrame(opPong, payload); err != nil && !errors.Is(err, ErrCloseSent) {
+			return err
+		}
+	case opPong:
+		if c.pongHandler != nil {
+			return c.pongHandler(string(payload))
+		}
+	case opClose:
+		closeErr := &CloseError{Code: CloseNoStatusReceived}
+		switch {
+		case len(payload) == 1:
+			return c.fail(CloseProtocolError, "bad close payload")
+		case len(payload) >= 2:
+			closeErr.Code = int(binary.BigEndian.Uint16(payload))
+			closeErr.Text = string(payload[2:])
+			if !validCloseCode(closeErr.Code) || !utf8.ValidString(closeErr.Text) {
+				return c.fail(CloseProtocolError, "bad close payload")
+			}
+		}
+		code := closeErr.Code
+		if code == CloseNoStatusReceived {
+			code = CloseNormalClosure
+		}
+		_ = c.writeClose(code, "")
+		_ = c.conn.Close()
+		return closeErr
+	}
+	return nil
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= 1000 && code <= 1011:
+		return code != 1004 && code != 1005 && code != 1006
+	}
+	return false
+}
+
+// fail closes the connection after a protocol violation by the peer.
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.CloseWithStatus(code, reason)
+	return &CloseError{Code: code, Text: reason}
+}
+
+func (c *Conn) writeClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	return c.writeFrame(opClose, payload)
+}
+
+type frameHeader struct {
+	fin     bool
+	rsv     byte
+	opcode  byte
+	masked  bool
+	length  int64
+	maskKey [4]byte
+}
+
+func (c *Conn) readFrameHeader() (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+	h.fin = b[0]&0x80 != 0
+	h.rsv = b[0] & 0x70
+	h.opcode = b[0] & 0x0f
+	h.masked = b[1]&0x80 != 0
+	h.length = int64(b[1] & 0x7f)
+	switch h.length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n>>63 != 0 {
+			return h, c.fail(CloseProtocolError, "bad frame length")
+		}
+		h.length = int64(n)
+	}
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.maskKey[:]); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// readPayload reads the payload of h as it arrives rather than allocating
+// the declared length up front, which the peer controls.
+func (c *Conn) readPayload(h frameHeader) ([]byte, error) {
+	var buf bytes.Buffer
+	if h.length <= payloadChunk {
+		buf.Grow(int(h.length))
+	}
+	if _, err := io.CopyN(&buf, c.br, h.length); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	payload := buf.Bytes()
+	if h.masked {
+		maskBytes(h.maskKey, payload)
+	}
+	return payload, nil
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == opClose {
+		c.closeSent = true
+	}
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+	var maskBit byte
+	if !c.isServer {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.isServer {
+		frame = append(frame, payload...)
+	} else {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(key, frame[start:])
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
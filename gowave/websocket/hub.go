@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultWriteTimeout = 10 * time.Second
+
+// Hub groups connections into named rooms for broadcasting. A connection
+// may be in several rooms; connections that fail a write are closed and
+// removed from every room.
+type Hub struct {
+	// WriteTimeout bounds each write of a broadcast so that one slow
+	// client can't stall the others for long.
+	WriteTimeout time.Duration
+
+	mu    sync.RWMutex
+	rooms map[string]map[*Conn]struct{}
+	conns map[*Conn]map[string]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		WriteTimeout: defaultWriteTimeout,
+		rooms:        make(map[string]map[*Conn]struct{}),
+		conns:        make(map[*Conn]map[string]struct{}),
+	}
+}
+
+func (h *Hub) Join(room string, c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*Conn]struct{})
+	}
+	h.rooms[room][c] = struct{}{}
+	if h.conns[c] == nil {
+		h.conns[c] = make(map[string]struct{})
+	}
+	h.conns[c][room] = struct{}{}
+}
+
+func (h *Hub) Leave(room string, c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(room, c)
+}
+
+func (h *Hub) leave(room string, c *Conn) {
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+	delete(h.conns[c], room)
+	if len(h.conns[c]) == 0 {
+		delete(h.conns, c)
+	}
+}
+
+// Remove takes c out of every room. Call it when a connection's read loop
+// ends.
+func (h *Hub) Remove(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for room := range h.conns[c] {
+		h.leave(room, c)
+	}
+}
+
+// Rooms returns the rooms c is in.
+func (h *Hub) Rooms(c *Conn) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.conns[c]))
+	for room := range h.conns[c] {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// Count returns the number of connections in room.
+func (h *Hub) Count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends a message to every connection in room except those in
+// except, returning the number of connections it reached.
+func (h *Hub) Broadcast(room string, typ MessageType, data []byte, except ...*Conn) int {
+	h.mu.RLock()
+	targets := make([]*Conn, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	var failed []*Conn
+	for _, c := range targets {
+		if contains(except, c) {
+			continue
+		}
+		if err := h.write(c, typ, data); err != nil {
+			failed = append(failed, c)
+			continue
+		}
+		sent++
+	}
+	for _, c := range failed {
+		h.Remove(c)
+		_ = c.CloseWithStatus(CloseGoingAway, "")
+	}
+	return sent
+}
+
+func (h *Hub) write(c *Conn, typ MessageType, data []byte) error {
+	if h.WriteTimeout > 0 {
+		_ = c.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+		defer c.SetWriteDeadline(time.Time{})
+	}
+	return c.WriteMessage(typ, data)
+}
+
+// Close closes every connection in the hub with CloseGoingAway.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	conns := h.conns
+	h.rooms = make(map[string]map[*Conn]struct{})
+	h.conns = make(map[*Conn]map[string]struct{})
+	h.mu.Unlock()
+	for c := range conns {
+		_ = c.CloseWithStatus(CloseGoingAway, "")
+	}
+}
+
+func contains(conns []*Conn, c *Conn) bool {
+	for _, other := range conns {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ChenGuo505/gowave/codec"
+)
+
+func newEchoServer(t *testing.T, u *Upgrader) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			typ, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string) *Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { _ = c.conn.Close() })
+	return c
+}
+
+// writeRawFrame writes a single masked client frame.
+func writeRawFrame(t *testing.T, c *Conn, fin bool, opcode byte, payload []byte) {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0, 0x80 | byte(len(payload)), 1, 2, 3, 4}
+	masked := append([]byte(nil), payload...)
+	maskBytes([4]byte{1, 2, 3, 4}, masked)
+	if _, err := c.conn.Write(append(frame, masked...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	c := dial(t, newEchoServer(t, &Upgrader{}))
+	messages := []struct {
+		typ  MessageType
+		data string
+	}{
+		{TextMessage, "hello"},
+		{BinaryMessage, "\x00\x01\x02"},
+		{TextMessage, strings.Repeat("x", 200)},
+		{BinaryMessage, strings.Repeat("y", 70000)},
+	}
+	for _, m := range messages {
+		if err := c.WriteMessage(m.typ, []byte(m.data)); err != nil {
+			t.Fatal(err)
+		}
+		typ, data, err := c.ReadMessage()
+		if err != nil || typ != m.typ || string(data) != m.data {
+			t.Errorf("Expected %d message of %d bytes, got %d of %d: %v", m.typ, len(m.data), typ, len(data), err)
+		}
+	}
+}
+
+func TestFragmentsAndControlFrames(t *testing.T) {
+	c := dial(t, newEchoServer(t, &Upgrader{}))
+	var pongs []string
+	c.SetPongHandler(func(data string) error {
+		pongs = append(pongs, data)
+		return nil
+	})
+	writeRawFrame(t, c, false, opText, []byte("hel"))
+	writeRawFrame(t, c, true, opPing, []byte("p1"))
+	writeRawFrame(t, c, true, opContinuation, []byte("lo"))
+	typ, data, err := c.ReadMessage()
+	if err != nil || typ != TextMessage || string(data) != "hello" {
+		t.Errorf("Expected the fragments to be joined, got %d %q %v", typ, data, err)
+	}
+	if len(pongs) != 1 || pongs[0] != "p1" {
+		t.Errorf("Expected a pong for the interleaved ping, got %v", pongs)
+	}
+
+	if err := c.CloseWithStatus(CloseGoingAway, "bye"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	closed := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_, _, err = c.ReadMessage()
+		closed <- err
+	}))
+	defer srv.Close()
+	c := dial(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	writeRawFrame(t, c, true, opClose, append(binary.BigEndian.AppendUint16(nil, 4000), "done"...))
+
+	var closeErr *CloseError
+	if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != 4000 || closeErr.Text != "done" {
+		t.Errorf("Expected the server to see close 4000 done, got %v", err)
+	}
+	// the server echoes the close code
+	if _, _, err := c.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != 4000 {
+		t.Errorf("Expected the close to be echoed, got %v", err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	url := newEchoServer(t, &Upgrader{ReadLimit: 10})
+	tests := []struct {
+		name  string
+		write func(c *Conn)
+		code  int
+	}{
+		{"read limit", func(c *Conn) { writeRawFrame(t, c, true, opBinary, make([]byte, 11)) }, CloseMessageTooBig},
+		{"fragmented read limit", func(c *Conn) {
+			writeRawFrame(t, c, false, opBinary, make([]byte, 6))
+			writeRawFrame(t, c, true, opContinuation, make([]byte, 6))
+		}, CloseMessageTooBig},
+		{"invalid utf-8", func(c *Conn) { writeRawFrame(t, c, true, opText, []byte{0xff, 0xfe}) }, CloseInvalidPayload},
+		{"unexpected continuation", func(c *Conn) { writeRawFrame(t, c, true, opContinuation, []byte("x")) }, CloseProtocolError},
+		{"fragmented ping", func(c *Conn) { writeRawFrame(t, c, false, opPing, nil) }, CloseProtocolError},
+		{"reserved opcode", func(c *Conn) { writeRawFrame(t, c, true, 0x3, nil) }, CloseProtocolError},
+		{"unmasked frame", func(c *Conn) { _, _ = c.conn.Write([]byte{0x81, 0x01, 'x'}) }, CloseProtocolError},
+	}
+	for _, tt := range tests {
+		c := dial(t, url)
+		tt.write(c)
+		var closeErr *CloseError
+		if _, _, err := c.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != tt.code {
+			t.Errorf("%s: expected close %d, got %v", tt.name, tt.code, err)
+		}
+	}
+}
+
+func TestDefaultReadLimit(t *testing.T) {
+	url := newEchoServer(t, &Upgrader{})
+	c := dial(t, url)
+	// a header declaring a 1TB payload, with no payload behind it
+	header := []byte{0x82, 0x80 | 127, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+	binary.BigEndian.PutUint64(header[2:10], 1<<40)
+	if _, err := c.conn.Write(header); err != nil {
+		t.Fatal(err)
+	}
+	var closeErr *CloseError
+	if _, _, err := c.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Errorf("Expected close %d, got %v", CloseMessageTooBig, err)
+	}
+}
+
+func TestReadPayloadUnlimited(t *testing.T) {
+	// without a limit the payload is still read as it arrives, so a short
+	// body ends in an error rather than a huge allocation
+	url := newEchoServer(t, &Upgrader{ReadLimit: -1})
+	c := dial(t, url)
+	header := []byte{0x82, 0x80 | 127, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+	binary.BigEndian.PutUint64(header[2:10], 1<<40)
+	if _, err := c.conn.Write(append(header, "short"...)); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.conn.(interface{ CloseWrite() error }).CloseWrite()
+	if _, _, err := c.ReadMessage(); err == nil {
+		t.Error("Expected the connection to end")
+	}
+}
+
+type upperCodec struct {
+	codec.StdJSON
+}
+
+func (upperCodec) Marshal(v any) ([]byte, error) {
+	b, err := codec.StdJSON{}.Marshal(v)
+	return bytes.ToUpper(b), err
+}
+
+func TestJSONUsesCodec(t *testing.T) {
+	old := codec.JSON
+	codec.JSON = upperCodec{}
+	defer func() { codec.JSON = old }()
+
+	c := dial(t, newEchoServer(t, &Upgrader{}))
+	if err := c.WriteJSON(map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := c.ReadJSON(&got); err != nil || got["A"] != "B" {
+		t.Errorf("Expected the swapped codec to be used, got %v %v", got, err)
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	u := &Upgrader{Subprotocols: []string{"v2", "v1"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r, nil)
+		if err == nil {
+			_ = c.Close()
+		}
+	}))
+	defer srv.Close()
+	valid := func() http.Header {
+		return http.Header{
+			"Connection":            {"keep-alive, Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(h http.Header)
+		status int
+	}{
+		{"valid", func(h http.Header) {}, http.StatusSwitchingProtocols},
+		{"no upgrade", func(h http.Header) { h.Del("Upgrade") }, http.StatusBadRequest},
+		{"bad version", func(h http.Header) { h.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired},
+		{"bad key", func(h http.Header) { h.Set("Sec-WebSocket-Key", "short") }, http.StatusBadRequest},
+		{"cross origin", func(h http.Header) { h.Set("Origin", "http://evil.example") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header = valid()
+		tt.modify(req.Header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+		if tt.status == http.StatusSwitchingProtocols && resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("Unexpected accept key %s", resp.Header.Get("Sec-WebSocket-Accept"))
+		}
+	}
+
+	ctx := context.Background()
+	c, _, err := Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), http.Header{"Sec-WebSocket-Protocol": {"v1, v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.conn.Close()
+	if c.Subprotocol() != "v2" {
+		t.Errorf("Expected the server's preferred subprotocol v2, got %q", c.Subprotocol())
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	hub := NewHub()
+	var wg sync.WaitGroup
+	joined := make(chan struct{}, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		wg.Add(1)
+		defer wg.Done()
+		hub.Join(r.URL.Query().Get("room"), c)
+		joined <- struct{}{}
+		defer hub.Remove(c)
+		for {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			hub.Broadcast(r.URL.Query().Get("room"), TextMessage, data, c)
+		}
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	alice, bob, carol := dial(t, url+"?room=a"), dial(t, url+"?room=a"), dial(t, url+"?room=b")
+	for i := 0; i < 3; i++ {
+		<-joined
+	}
+	if hub.Count("a") != 2 || hub.Count("b") != 1 {
+		t.Fatalf("Unexpected room sizes a=%d b=%d", hub.Count("a"), hub.Count("b"))
+	}
+	if err := alice.WriteMessage(TextMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := bob.ReadMessage(); err != nil || string(data) != "hi" {
+		t.Errorf("Expected bob to get hi, got %q %v", data, err)
+	}
+	if n := hub.Broadcast("b", TextMessage, []byte("only b")); n != 1 {
+		t.Errorf("Expected one recipient in b, got %d", n)
+	}
+	if _, data, err := carol.ReadMessage(); err != nil || string(data) != "only b" {
+		t.Errorf("Expected carol to get only b, got %q %v", data, err)
+	}
+
+	_ = bob.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.Count("a") != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hub.Count("a") != 1 {
+		t.Errorf("Expected bob to leave room a, got %d connections", hub.Count("a"))
+	}
+	hub.Close()
+	var closeErr *CloseError
+	if _, _, err := alice.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway {
+		t.Errorf("Expected close going away, got %v", err)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package gowave
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChenGuo505/gowave/websocket"
+)
+
+func TestContextUpgrade(t *testing.T) {
+	engine := newTestEngine()
+	g := engine.Group("api")
+	statuses := make(chan [2]int, 1)
+	g.Get("/ws/:room", func(ctx *Context) {
+		conn, err := ctx.Upgrade()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		statuses <- [2]int{ctx.Writer().Status(), ctx.StatusCode}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteJSON(map[string]string{"room": ctx.Param("room"), "msg": string(data)})
+		}
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	c, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/api/ws/lobby", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := c.WriteMessage(websocket.TextMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	var reply map[string]string
+	if err := c.ReadJSON(&reply); err != nil || reply["room"] != "lobby" || reply["msg"] != "hi" {
+		t.Errorf("Unexpected reply %v %v", reply, err)
+	}
+	if status := <-statuses; status != [2]int{http.StatusSwitchingProtocols, http.StatusSwitchingProtocols} {
+		t.Errorf("Expected status and StatusCode 101 after the upgrade, got %v", status)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/ws/lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a plain GET, got %d", resp.StatusCode)
+	}
+}